@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ---------- anchor checking (--strict) ----------
+
+// reHTMLID finds id="x" and name="x" in raw HTML, which #x may target too.
+var reHTMLID = regexp.MustCompile(`\b(?:id|name)\s*=\s*["']([^"']+)["']`)
+
+// brokenAnchor is a #link that matches nothing in its document.
+type brokenAnchor struct {
+	line   int // 1-based, in the Markdown source
+	anchor string
+}
+
+// brokenAnchors lists the #anchor links in raw that match no heading
+// anchor (exactly: fragments are case-sensitive), footnote or HTML id.
+func brokenAnchors(raw string) []brokenAnchor {
+	known := map[string]bool{}
+	for _, h := range parseHeadings(raw, false) {
+		known[h.anchor] = true
+	}
+	for _, mm := range reHTMLID.FindAllStringSubmatch(raw, -1) {
+		known[mm[1]] = true
+	}
+	links, footnotes := parseLinks(raw, "")
+	for label := range footnotes {
+		known["fn-"+label] = true
+	}
+	var out []brokenAnchor
+	for _, l := range links {
+		if !strings.HasPrefix(l.target, "#") {
+			continue
+		}
+		anc := strings.TrimPrefix(l.target, "#")
+		if dec, err := url.PathUnescape(anc); err == nil {
+			anc = dec
+		}
+		if anc == "" || known[anc] {
+			continue
+		}
+		out = append(out, brokenAnchor{line: strings.Count(raw[:l.source], "\n") + 1, anchor: l.target})
+	}
+	return out
+}
+
+// checkAnchors reports the broken anchors of every file, as
+// "file:line: broken anchor #x", and errors when there are any.
+func checkAnchors(w io.Writer, docs []document) error {
+	n := 0
+	for _, doc := range docs {
+		for _, b := range brokenAnchors(doc.raw) {
+			fmt.Fprintf(w, "%s:%d: broken anchor %s\n", doc.name, b.line, b.anchor)
+			n++
+		}
+	}
+	switch n {
+	case 0:
+		return nil
+	case 1:
+		return errors.New("1 broken anchor")
+	}
+	return fmt.Errorf("%d broken anchors", n)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// ---------- bench (hidden subcommand) ----------
+
+// benchPhase is one stage of getting a document on screen, timed over
+// every run.
+type benchPhase struct {
+	name  string
+	times []time.Duration
+}
+
+// benchCommand is "mdnfo bench file.md": it runs the stages the viewer
+// goes through for each render, --runs times, and prints how long each
+// took, for reports of slowness on large documents. The root command's
+// flags are persistent, so it renders what --print would, with the same
+// --style, --mono and the rest, at --cols or the terminal's width.
+func benchCommand(flags *startFlags) *cobra.Command {
+	var runs int
+	cmd := &cobra.Command{
+		Use:    "bench file.md",
+		Short:  "Time rendering, stream tokenizing and post effects for a file",
+		Hidden: true,
+		Args:   cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if runs < 1 {
+				return fmt.Errorf("invalid --runs: %d (use 1 or more)", runs)
+			}
+			doc, err := readDocument(args[0], flags.encoding)
+			if err != nil {
+				return err
+			}
+			return runBench(cmd.OutOrStdout(), doc, *flags, runs)
+		},
+	}
+	cmd.Flags().IntVarP(&runs, "runs", "n", 10, "how many times to run each stage")
+	return cmd
+}
+
+// runBench times doc's render stages runs times and writes the report
+// to w.
+func runBench(w io.Writer, doc document, flags startFlags, runs int) error {
+	width := flags.cols
+	if width == 0 {
+		width, _ = terminalSize()
+	}
+	flags.print = true // no terminal queries
+	m := initialModel(doc.name, doc.raw, flags.style, flags.wrap, doc.mod, doc.size, flags)
+	m.view.Width = width
+	phases, err := m.bench(width, runs)
+	if err != nil {
+		return err
+	}
+	writeBench(w, &m, width, runs, phases)
+	return nil
+}
+
+// bench runs the render pipeline runs times at width, skipping the render
+// cache, and returns each stage's timings.
+func (m *model) bench(width, runs int) ([]benchPhase, error) {
+	phases := []benchPhase{{name: "render"}, {name: "stream tokens"}, {name: "post effects"}}
+	for range runs {
+		start := time.Now()
+		out, err := m.renderFresh(width)
+		if err != nil {
+			return nil, err
+		}
+		if m.renderTimedOut {
+			// the fallback's timing would pass for glamour's
+			return nil, fmt.Errorf("%s: rendering took over %s (raise --render-timeout, or 0 for no limit)", m.filename, m.renderTimeout)
+		}
+		phases[0].times = append(phases[0].times, time.Since(start))
+
+		m.renderedFull = out
+		start = time.Now()
+		m.prepareStreamTokens()
+		phases[1].times = append(phases[1].times, time.Since(start))
+
+		start = time.Now()
+		m.applyPostEffects(out)
+		phases[2].times = append(phases[2].times, time.Since(start))
+	}
+	return phases, nil
+}
+
+func writeBench(w io.Writer, m *model, width, runs int, phases []benchPhase) {
+	lines := strings.Count(m.renderedFull, "\n") + 1
+	fmt.Fprintf(w, "%s: %s, %d rendered lines at %d columns, style %s, %d runs\n\n",
+		m.filename, humanSize(m.fileSize), lines, width, m.theme, runs)
+	fmt.Fprintf(w, "%-14s %10s %10s %10s\n", "stage", "min", "mean", "max")
+	var total time.Duration
+	for _, p := range phases {
+		lo, hi, sum := p.times[0], p.times[0], time.Duration(0)
+		for _, t := range p.times {
+			if t < lo {
+				lo = t
+			}
+			if t > hi {
+				hi = t
+			}
+			sum += t
+		}
+		mean := sum / time.Duration(len(p.times))
+		total += mean
+		fmt.Fprintf(w, "%-14s %10s %10s %10s\n", p.name, benchTime(lo), benchTime(mean), benchTime(hi))
+	}
+	fmt.Fprintf(w, "%-14s %10s %10s\n", "total", "", benchTime(total))
+}
+
+// benchTime rounds d to a precision that still tells runs apart.
+func benchTime(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond).String()
+	default:
+		return d.Round(time.Microsecond).String()
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-isatty"
+)
+
+// ---------- color capabilities ----------
+
+// colorLevel is the --color setting; colorAuto detects it.
+type colorLevel int
+
+const (
+	colorAuto colorLevel = iota
+	color16
+	color256
+	colorTrue
+)
+
+// probeTimeout bounds the wait for the terminal's answer; terminals that
+// don't know the query still answer the DA1 sent after it at once.
+const probeTimeout = 150 * time.Millisecond
+
+// detectColorCaps works out the terminal's colors: forced by --color, else
+// from COLORTERM and TERM, then terminfo, then (when probe is set and
+// stdout is a terminal) by asking the terminal itself.
+func detectColorCaps(force colorLevel, probe bool) (truecolor bool, palette256 bool) {
+	switch force {
+	case color16:
+		return false, false
+	case color256:
+		return false, true
+	case colorTrue:
+		return true, true
+	}
+	tc := strings.ToLower(os.Getenv("COLORTERM"))
+	if strings.Contains(tc, "truecolor") || strings.Contains(tc, "24bit") {
+		truecolor = true
+	}
+	termVar := strings.ToLower(os.Getenv("TERM"))
+	if strings.Contains(termVar, "256color") {
+		palette256 = true
+	}
+	if strings.HasSuffix(termVar, "-direct") {
+		truecolor = true
+	}
+	if !truecolor && termVar != "" {
+		rgb, colors := terminfoColors(termVar)
+		truecolor = truecolor || rgb
+		palette256 = palette256 || colors >= 256
+	}
+	if !truecolor && probe && isatty.IsTerminal(os.Stdout.Fd()) {
+		truecolor = queryTrueColor(probeTimeout)
+	}
+	if truecolor {
+		palette256 = true
+	}
+	return
+}
+
+var reColorsCap = regexp.MustCompile(`\bcolors#(0x[0-9a-fA-F]+|\d+)`)
+
+// terminfoColors reads the RGB/Tc flags and the colors number of the
+// terminfo entry for termName, using infocmp when it's installed.
+func terminfoColors(termName string) (rgb bool, colors int) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "infocmp", "-x", termName).Output()
+	if err != nil {
+		return false, 0
+	}
+	for _, f := range strings.FieldsFunc(string(out), func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	}) {
+		if f == "RGB" || f == "Tc" {
+			rgb = true
+		}
+	}
+	if mm := reColorsCap.FindStringSubmatch(string(out)); mm != nil {
+		if n, err := strconv.ParseInt(mm[1], 0, 64); err == nil {
+			colors = int(n)
+		}
+	}
+	return rgb, colors
+}
+
+// reTcapYes is an XTGETTCAP "valid" reply for RGB or Tc (hex-encoded).
+var reTcapYes = regexp.MustCompile(`\x1bP1\+r(524742|5463)`)
+
+// queryTrueColor asks the terminal whether it has the RGB or Tc
+// capability (XTGETTCAP).
+func queryTrueColor(timeout time.Duration) bool {
+	return reTcapYes.Match(queryTerminal("\x1bP+q524742;5463\x1b\\", timeout))
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ---------- config file ----------
+
+// config mirrors config.toml:
+//
+//	[keys]
+//	scroll-down = ["j", "down"]
+//	quit = "q"
+type config struct {
+	Keys map[string]keyList `toml:"keys"`
+}
+
+// keyList accepts either a single key string or an array of them.
+type keyList []string
+
+func (k *keyList) UnmarshalTOML(v any) error {
+	switch v := v.(type) {
+	case string:
+		*k = keyList{v}
+	case []any:
+		for _, e := range v {
+			s, ok := e.(string)
+			if !ok {
+				return fmt.Errorf("key binding must be a string, got %T", e)
+			}
+			*k = append(*k, s)
+		}
+	default:
+		return fmt.Errorf("key binding must be a string or array, got %T", v)
+	}
+	return nil
+}
+
+// configDir is where mdnfo keeps its files: mdnfo under the user config
+// dir, which is $XDG_CONFIG_HOME when set, on every OS, else the OS's own
+// (~/.config on Linux, ~/Library/Application Support on macOS).
+func configDir() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if !filepath.IsAbs(dir) {
+		var err error
+		if dir, err = os.UserConfigDir(); err != nil {
+			return ""
+		}
+	}
+	return filepath.Join(dir, "mdnfo")
+}
+
+// defaultConfigPath is config.toml in configDir.
+func defaultConfigPath() string {
+	dir := configDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "config.toml")
+}
+
+// loadConfig reads the config file, if any. A missing file is only an
+// error when required, as for one named with --config.
+func loadConfig(path string, required bool) (config, error) {
+	var cfg config
+	if path == "" {
+		return cfg, nil
+	}
+	if _, err := toml.DecodeFile(path, &cfg); err != nil {
+		if errors.Is(err, fs.ErrNotExist) && !required {
+			return config{}, nil
+		}
+		return config{}, fmt.Errorf("config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// keymap builds the key bindings, with the config's [keys] overrides applied.
+func (c config) keymap() (keymap, error) {
+	overrides := map[string][]string{}
+	for name, keys := range c.Keys {
+		overrides[name] = keys
+	}
+	km, err := newKeymap(overrides)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	return km, nil
+}
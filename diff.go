@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ---------- diff view (--diff) ----------
+
+// diffGutter is the width of the change marker put before each line.
+const diffGutter = 2
+
+// maxDiffEdits bounds the diff's search; documents that differ by more
+// than this many lines show the rest as one replaced block.
+const maxDiffEdits = 2000
+
+// diffContext is how many lines are left above a change jumped to.
+const diffContext = 2
+
+const (
+	diffSame   = ' '
+	diffDelete = '-'
+	diffInsert = '+'
+)
+
+// renderDiff renders the base document and the current one for the same
+// canvas and interleaves them: lines only in the base marked - in red,
+// lines only in the current document marked + in green.
+func (m *model) renderDiff(width int) (string, error) {
+	base, cur := *m, *m
+	base.rawMarkdown, base.diffName, cur.diffName = m.diffBase, "", ""
+	old, err := base.renderFresh(width - diffGutter)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", m.diffName, err)
+	}
+	out, err := cur.renderFresh(width - diffGutter)
+	if err != nil {
+		return "", err
+	}
+	// a half that timed out isn't taken up when it finishes, as it's only
+	// half the diff; reloading renders both again
+	m.renderTimedOut = base.renderTimedOut || cur.renderTimedOut
+	a := strings.Split(strings.TrimRight(old, "\n"), "\n")
+	b := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	plain := func(lines []string) []string {
+		p := make([]string, len(lines))
+		for i, l := range lines {
+			p[i] = strings.TrimRight(stripANSI(l), " ")
+		}
+		return p
+	}
+
+	var s strings.Builder
+	i, j := 0, 0
+	for n, op := range diffLines(plain(a), plain(b)) {
+		if n > 0 {
+			s.WriteByte('\n')
+		}
+		switch op {
+		case diffDelete:
+			s.WriteString("\x1b[31m-\x1b[0m " + a[i])
+			i++
+		case diffInsert:
+			s.WriteString("\x1b[32m+\x1b[0m " + b[j])
+			j++
+		default:
+			s.WriteString("  " + b[j])
+			i++
+			j++
+		}
+	}
+	return s.String(), nil
+}
+
+// diffLines is a shortest edit script turning a into b, one op per line
+// of the merged result (Myers' algorithm).
+func diffLines(a, b []string) []byte {
+	// the common ends need no search
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+	ops := make([]byte, 0, len(a)+len(b))
+	for range pre {
+		ops = append(ops, diffSame)
+	}
+	ops = append(ops, myers(a[pre:len(a)-suf], b[pre:len(b)-suf])...)
+	for range suf {
+		ops = append(ops, diffSame)
+	}
+	return ops
+}
+
+func myers(a, b []string) []byte {
+	n, m := len(a), len(b)
+	limit := min(n+m, maxDiffEdits)
+	v := make([]int, 2*limit+3)
+	off := limit + 1
+	var trace [][]int // v before each round d, keys -d..d
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v[off-d:off+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1] // down: insert from b
+			} else {
+				x = v[off+k-1] + 1 // right: delete from a
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, n, m)
+			}
+		}
+	}
+
+	// too different to be worth it: replace everything
+	ops := make([]byte, 0, n+m)
+	for range n {
+		ops = append(ops, diffDelete)
+	}
+	for range m {
+		ops = append(ops, diffInsert)
+	}
+	return ops
+}
+
+// backtrack walks the saved rounds from (n, m) back to the start,
+// collecting the ops in reverse.
+func backtrack(trace [][]int, n, m int) []byte {
+	var rev []byte
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		vd := trace[d] // index k+d
+		k := x - y
+		var pk int
+		if k == -d || (k != d && vd[k-1+d] < vd[k+1+d]) {
+			pk = k + 1
+		} else {
+			pk = k - 1
+		}
+		px := vd[pk+d]
+		py := px - pk
+		for x > px && y > py {
+			rev = append(rev, diffSame)
+			x--
+			y--
+		}
+		if x == px {
+			rev = append(rev, diffInsert)
+		} else {
+			rev = append(rev, diffDelete)
+		}
+		x, y = px, py
+	}
+	for ; x > 0; x-- {
+		rev = append(rev, diffSame)
+	}
+	ops := make([]byte, len(rev))
+	for i, op := range rev {
+		ops[len(rev)-1-i] = op
+	}
+	return ops
+}
+
+// diffHunks lists the first line of each run of changed lines in the
+// rendered diff.
+func diffHunks(plain []string) []int {
+	var hunks []int
+	prev := false
+	for i, l := range plain {
+		changed := strings.HasPrefix(l, "+ ") || strings.HasPrefix(l, "- ") || l == "+" || l == "-"
+		if changed && !prev {
+			hunks = append(hunks, i)
+		}
+		prev = changed
+	}
+	return hunks
+}
+
+// compareWith shows the document as a diff against base; a zero base
+// leaves it alone.
+func (m *model) compareWith(base document) {
+	if base.name == "" {
+		return
+	}
+	m.diffBase, m.diffName = base.raw, base.name
+}
+
+// jumpHunk scrolls to the next (dir > 0) or previous change after or
+// before the top of the screen, wrapping around.
+func (m *model) jumpHunk(dir int) {
+	if m.diffName == "" {
+		m.flash("not comparing files (start with --diff old.md new.md)")
+		return
+	}
+	n := len(m.diffHunks)
+	if n == 0 {
+		m.flash("no changes")
+		return
+	}
+	i := -1
+	if h := m.diffHunk; h >= 0 && h < n && m.view.YOffset == m.hunkOffset(h) {
+		// still where the last jump left off, which on a short document
+		// may not be the hunk's own line
+		i = (h + dir + n) % n
+	} else if dir > 0 {
+		// hunks are shown a couple of lines below the top, for context
+		i = 0
+		for h, line := range m.diffHunks {
+			if line > m.view.YOffset+diffContext {
+				i = h
+				break
+			}
+		}
+	} else {
+		i = n - 1
+		for h := n - 1; h >= 0; h-- {
+			if m.diffHunks[h] < m.view.YOffset+diffContext {
+				i = h
+				break
+			}
+		}
+	}
+	m.record()
+	m.diffHunk = i
+	m.view.SetYOffset(m.hunkOffset(i))
+	m.flash(fmt.Sprintf("change %d/%d", i+1, n))
+}
+
+// hunkOffset is the scroll offset that shows hunk i.
+func (m *model) hunkOffset(i int) int {
+	return clamp(m.diffHunks[i]-diffContext, 0, max(0, m.totalLines-m.view.Height))
+}
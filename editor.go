@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ---------- open in an editor (e) ----------
+
+// editorDoneMsg is sent when the editor started by e exits.
+type editorDoneMsg struct{ err error }
+
+// sourceLineMap maps each of n rendered lines to the source line, from 0,
+// it roughly came from. glamour doesn't keep track, so the located
+// headings are fixed points, along with both ends of the document, and
+// the lines between two of them are spread evenly over the source lines
+// between.
+func sourceLineMap(hs []heading, n, sourceLines int) []int {
+	type point struct{ rendered, source int }
+	points := []point{{0, 0}}
+	for _, h := range hs {
+		last := points[len(points)-1]
+		// a heading found out of order would fold the map back on itself
+		if h.renderedLine > last.rendered && h.line >= last.source {
+			points = append(points, point{h.renderedLine, h.line})
+		}
+	}
+	if last := points[len(points)-1]; n > last.rendered {
+		points = append(points, point{n, max(sourceLines, last.source)})
+	}
+
+	out := make([]int, n)
+	p := 0
+	for r := range out {
+		for p+1 < len(points) && points[p+1].rendered <= r {
+			p++
+		}
+		a := points[p]
+		if p+1 == len(points) {
+			out[r] = a.source
+			continue
+		}
+		b := points[p+1]
+		out[r] = a.source + (r-a.rendered)*(b.source-a.source)/(b.rendered-a.rendered)
+	}
+	return out
+}
+
+// buildSourceMap is sourceLineMap for the whole rendered document, not
+// just what has streamed in so far.
+func (m *model) buildSourceMap() []int {
+	plain := strings.TrimRight(stripANSI(m.renderedFull), "\n")
+	hs := append([]heading(nil), m.headings...)
+	loc := newTextLocator(plain)
+	for i := range hs {
+		hs[i].renderedLine = loc.next(hs[i].text)
+	}
+	return sourceLineMap(hs, strings.Count(plain, "\n")+1, strings.Count(m.rawMarkdown, "\n")+1)
+}
+
+// editSource opens the file in $VISUAL or $EDITOR at the source line of
+// the top line on screen, "+N" being understood by vi, emacs, nano, micro
+// and most others. With no editor set it only shows file:line. The file
+// is reloaded when the editor exits.
+func (m *model) editSource() tea.Cmd {
+	if m.filename == stdinName || isURL(m.filename) {
+		m.flash("no file to edit")
+		return m.tick()
+	}
+	line := 1
+	if top := m.view.YOffset; top < len(m.sourceMap) {
+		line = m.sourceMap[top] + 1
+	}
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		m.flash(fmt.Sprintf("%s:%d (set $EDITOR to open it there)", m.filename, line))
+		return m.tick()
+	}
+	args = append(args, fmt.Sprintf("+%d", line), m.filename)
+	cmd := exec.Command(args[0], args[1:]...)
+	return tea.ExecProcess(cmd, func(err error) tea.Msg { return editorDoneMsg{err} })
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// ---------- text encodings ----------
+
+// encodings lists the --encoding values; "auto" keeps valid UTF-8 as is
+// and guesses between CP437 and Latin-1 for anything else.
+var encodings = []string{"auto", "utf8", "cp437", "latin1"}
+
+func validateEncoding(enc string) error {
+	for _, e := range encodings {
+		if enc == e {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid --encoding %q; valid: %s", enc, strings.Join(encodings, ", "))
+}
+
+// decodeText converts the bytes of the file called name to UTF-8.
+func decodeText(b []byte, name, enc string) (string, error) {
+	if enc == "auto" || enc == "" {
+		if utf8.Valid(b) {
+			return string(b), nil
+		}
+		enc = guessEncoding(b, name)
+	}
+	switch enc {
+	case "cp437":
+		out, err := charmap.CodePage437.NewDecoder().Bytes(b)
+		return string(out), err
+	case "latin1":
+		out, err := charmap.ISO8859_1.NewDecoder().Bytes(b)
+		return string(out), err
+	}
+	return string(b), nil
+}
+
+// isSceneFile reports whether name is a .nfo, .diz or .ans file: CP437
+// text art rather than Markdown.
+func isSceneFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".nfo", ".diz", ".ans":
+		return true
+	}
+	return false
+}
+
+// guessEncoding picks CP437 for scene files and for text whose high bytes
+// are mostly CP437's box-drawing and block range (0xB0-0xDF); otherwise
+// Latin-1, whose accented letters sit higher.
+func guessEncoding(b []byte, name string) string {
+	if isSceneFile(name) {
+		return "cp437"
+	}
+	high, boxes := 0, 0
+	for _, c := range b {
+		if c >= 0x80 {
+			high++
+			if c >= 0xB0 && c <= 0xDF {
+				boxes++
+			}
+		}
+	}
+	if high > 0 && boxes*2 >= high {
+		return "cp437"
+	}
+	return "latin1"
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+)
+
+// ---------- HTML export ----------
+
+const exportCSS = `body{margin:0;background:#111;color:#ddd;font:16px/1.6 system-ui,sans-serif}
+main{max-width:48rem;margin:2rem auto;padding:0 1rem}
+a{color:#6cf}h1,h2,h3,h4,h5,h6{color:#fff;line-height:1.25}
+code,pre{font-family:ui-monospace,Menlo,Consolas,monospace;background:#1c1c1c}
+code{padding:.1em .3em;border-radius:3px}pre{padding:1em;overflow:auto}pre code{padding:0}
+blockquote{margin:0;padding:0 1em;border-left:3px solid #444;color:#aaa}
+table{border-collapse:collapse}th,td{border:1px solid #444;padding:.3em .6em}
+img{max-width:100%}hr{border:0;border-top:1px solid #444}`
+
+// slugIDs gives headings the same anchors the viewer uses (slugify), with
+// GitHub-style -1, -2 suffixes for repeats.
+type slugIDs struct {
+	seen map[string]bool
+}
+
+func (s *slugIDs) Generate(value []byte, _ ast.NodeKind) []byte {
+	return []byte(s.next(string(value)))
+}
+
+// next returns the anchor for the next heading titled text.
+func (s *slugIDs) next(text string) string {
+	base := slugify(text)
+	if base == "" {
+		base = "heading"
+	}
+	id := base
+	for i := 1; s.seen[id]; i++ {
+		id = fmt.Sprintf("%s-%d", base, i)
+	}
+	s.seen[id] = true
+	return id
+}
+
+func (s *slugIDs) Put(value []byte) { s.seen[string(value)] = true }
+
+// exportHTML converts raw Markdown to a self-contained HTML page.
+func exportHTML(w io.Writer, raw, title string) error {
+	md := goldmark.New(
+		goldmark.WithExtensions(extension.GFM, extension.Footnote),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+	)
+	var body bytes.Buffer
+	ctx := parser.NewContext(parser.WithIDs(&slugIDs{seen: map[string]bool{}}))
+	if err := md.Convert([]byte(raw), &body, parser.WithContext(ctx)); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>\n%s\n</style>\n</head>\n<body>\n<main>\n%s</main>\n</body>\n</html>\n",
+		html.EscapeString(title), exportCSS, body.String())
+	return err
+}
+
+// exportHTMLFile writes the page to path, or to stdout when path is "-".
+func exportHTMLFile(path string, doc document) error {
+	title := filepath.Base(doc.name)
+	if hs := parseHeadings(doc.raw, false); len(hs) > 0 {
+		title = hs[0].text
+	}
+	if path == "-" {
+		return exportHTML(os.Stdout, doc.raw, title)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := exportHTML(f, doc.raw, title); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
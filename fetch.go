@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ---------- documents over HTTP ----------
+
+const (
+	fetchTimeout = 15 * time.Second
+	maxFetchSize = 10 << 20 // bytes; a README, not a download
+	userAgent    = "mdnfo (terminal Markdown viewer)"
+)
+
+// isURL reports whether name is an http(s) URL rather than a file path.
+func isURL(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// fetch GETs rawURL and returns the body. Anything but a 200 whose
+// content type passes accepted is an error.
+func fetch(rawURL, accept string, accepted func(contentType string) bool) ([]byte, http.Header, error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", accept)
+	resp, err := (&http.Client{Timeout: fetchTimeout}).Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("%s: %s", rawURL, resp.Status)
+	}
+	if ct := resp.Header.Get("Content-Type"); !accepted(ct) {
+		return nil, nil, fmt.Errorf("%s: unexpected content type %s", rawURL, ct)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxFetchSize+1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", rawURL, err)
+	}
+	if len(b) > maxFetchSize {
+		return nil, nil, fmt.Errorf("%s: larger than %d MiB", rawURL, maxFetchSize>>20)
+	}
+	return b, resp.Header, nil
+}
+
+// fetchDocument GETs a Markdown or text document; Last-Modified, when
+// sent, becomes the document's time.
+func fetchDocument(rawURL, enc string) (document, error) {
+	b, hdr, err := fetch(rawURL, "text/markdown, text/plain;q=0.9, text/*;q=0.8", textContent)
+	if err != nil {
+		return document{}, err
+	}
+	// the path's extension picks the encoding guess, as for files
+	name := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		name = u.Path
+	}
+	raw, err := decodeText(b, name, enc)
+	if err != nil {
+		return document{}, fmt.Errorf("%s: %w", rawURL, err)
+	}
+	mod := time.Now()
+	if t, err := http.ParseTime(hdr.Get("Last-Modified")); err == nil {
+		mod = t
+	}
+	return document{name: rawURL, raw: raw, mod: mod, size: int64(len(b))}, nil
+}
+
+// textContent accepts text/* and the Markdown types; a missing header is
+// given the benefit of the doubt.
+func textContent(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mt, "text/") || mt == "application/markdown" || mt == "application/x-markdown"
+}
+
+// fetchStyle GETs a glamour JSON style; raw file hosts often serve JSON
+// as text/plain, so text is fine too.
+func fetchStyle(rawURL string) ([]byte, error) {
+	b, _, err := fetch(rawURL, "application/json, text/plain;q=0.9", func(ct string) bool {
+		mt, _, _ := mime.ParseMediaType(ct)
+		return textContent(ct) || mt == "application/json"
+	})
+	return b, err
+}
+
+// resolveURL resolves a link found in the document at base.
+func resolveURL(base, ref string) (string, bool) {
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", false
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return "", false
+	}
+	return b.ResolveReference(r).String(), true
+}
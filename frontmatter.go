@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ---------- front matter (--front-matter) ----------
+
+// frontMatterMode is what becomes of a leading YAML (---) or TOML (+++)
+// front matter block, --front-matter.
+type frontMatterMode int
+
+const (
+	frontMatterShow frontMatterMode = iota // not rendered; listed in the info panel
+	frontMatterHide                        // not rendered, not listed
+	frontMatterRaw                         // rendered as ordinary Markdown
+)
+
+// frontMatterEnd is the length of the front matter block raw starts with,
+// closing delimiter line included, or 0 when there is none. A block is
+// only taken as front matter once its closing delimiter is found.
+func frontMatterEnd(raw string) int {
+	first, rest, ok := strings.Cut(raw, "\n")
+	if !ok {
+		return 0
+	}
+	var closers []string
+	switch strings.TrimRight(first, " \r") {
+	case "---":
+		closers = []string{"---", "..."}
+	case "+++":
+		closers = []string{"+++"}
+	default:
+		return 0
+	}
+	end := len(first) + 1
+	for rest != "" {
+		line, after, found := strings.Cut(rest, "\n")
+		end += len(line)
+		if found {
+			end++
+		}
+		for _, c := range closers {
+			if strings.TrimRight(line, " \r") == c {
+				return end
+			}
+		}
+		rest = after
+	}
+	return 0
+}
+
+// markdownBody is raw as it is rendered and indexed: any front matter
+// blanked out, unless mode is frontMatterRaw. Blanking keeps every byte
+// offset and line number, so task toggles and file:line reports still
+// point into the file.
+func markdownBody(raw string, mode frontMatterMode) string {
+	end := frontMatterEnd(raw)
+	if end == 0 || mode == frontMatterRaw {
+		return raw
+	}
+	b := []byte(raw)
+	for i := range end {
+		if b[i] != '\n' && b[i] != '\r' {
+			b[i] = ' '
+		}
+	}
+	return string(b)
+}
+
+// markdown is the document as rendered and indexed; see markdownBody and,
+// with --section, sectionBody. Files shown verbatim keep every line.
+func (m *model) markdown() string {
+	if m.rawMode() {
+		return m.rawMarkdown
+	}
+	return sectionOf(markdownBody(m.rawMarkdown, m.frontMatter), m.section)
+}
+
+// frontMatterField is one top-level key of the front matter.
+type frontMatterField struct{ key, value string }
+
+// frontMatterFields lists the top-level keys of raw's front matter in
+// order, for the info panel. TOML is decoded properly; YAML is read as
+// "key: value" lines, with an indented "- item" list joined by commas.
+func frontMatterFields(raw string) []frontMatterField {
+	end := frontMatterEnd(raw)
+	if end == 0 {
+		return nil
+	}
+	block := strings.TrimSuffix(strings.ReplaceAll(raw[:end], "\r\n", "\n"), "\n")
+	lines := strings.Split(block, "\n")
+	lines = lines[1 : len(lines)-1] // between the delimiters
+	if strings.HasPrefix(raw, "+++") {
+		return tomlFields(strings.Join(lines, "\n"))
+	}
+
+	var fields []frontMatterField
+	for _, l := range lines {
+		trimmed := strings.TrimSpace(l)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+		case l[0] == ' ' || l[0] == '\t':
+			if item, ok := strings.CutPrefix(trimmed, "- "); ok && len(fields) > 0 {
+				f := &fields[len(fields)-1]
+				if f.value != "" {
+					f.value += ", "
+				}
+				f.value += unquote(item)
+			}
+		default:
+			if k, v, ok := strings.Cut(l, ":"); ok {
+				fields = append(fields, frontMatterField{strings.TrimSpace(k), unquote(strings.TrimSpace(v))})
+			}
+		}
+	}
+	return fields
+}
+
+func tomlFields(s string) []frontMatterField {
+	var data map[string]any
+	md, err := toml.Decode(s, &data)
+	if err != nil {
+		return []frontMatterField{{"error", err.Error()}}
+	}
+	var fields []frontMatterField
+	for _, k := range md.Keys() {
+		// tables show as their dotted leaf keys
+		var v any = data
+		for _, part := range k {
+			if t, ok := v.(map[string]any); ok {
+				v = t[part]
+			}
+		}
+		switch v := v.(type) {
+		case map[string]any:
+		case []any:
+			parts := make([]string, len(v))
+			for i, x := range v {
+				parts[i] = fmt.Sprint(x)
+			}
+			fields = append(fields, frontMatterField{k.String(), strings.Join(parts, ", ")})
+		default:
+			fields = append(fields, frontMatterField{k.String(), fmt.Sprint(v)})
+		}
+	}
+	return fields
+}
+
+// unquote strips one pair of matching quotes around a YAML scalar.
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
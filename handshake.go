@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ---------- dial-up handshake ----------
+
+// handshakeScript is the modem chatter shown before the stream, each line
+// appearing at its offset from the start. {baud} is the connect rate.
+var handshakeScript = []struct {
+	at   time.Duration
+	text string
+}{
+	{0, "ATZ"},
+	{300 * time.Millisecond, "OK"},
+	{500 * time.Millisecond, "ATDT 555-0199"},
+	{1100 * time.Millisecond, "RING"},
+	{1500 * time.Millisecond, "CARRIER {baud}"},
+	{1700 * time.Millisecond, "PROTOCOL: LAP-M"},
+	{1900 * time.Millisecond, "CONNECT {baud}"},
+}
+
+const handshakeDuration = 2400 * time.Millisecond
+
+// handshakeText is the part of the script due by now.
+func (m *model) handshakeText(now time.Time) string {
+	elapsed := now.Sub(m.handshakeStart)
+	baud := strconv.Itoa(m.baudrate)
+	var lines []string
+	for _, step := range handshakeScript {
+		if step.at > elapsed {
+			break
+		}
+		lines = append(lines, "  "+strings.ReplaceAll(step.text, "{baud}", baud))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// endHandshake hangs up the chatter and starts the document stream.
+func (m *model) endHandshake() {
+	m.handshaking = false
+	m.txStart = time.Now()
+	m.txLastAvail = 0
+	m.refreshContent()
+	m.buildIndexes()
+}
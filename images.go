@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-isatty"
+)
+
+// ---------- inline images ----------
+
+type graphicsProto int
+
+const (
+	graphicsNone  graphicsProto = iota
+	graphicsKitty               // kitty graphics protocol (APC _G)
+	graphicsITerm               // iTerm2 inline images (OSC 1337)
+	graphicsSixel               // DEC SIXEL (DCS q)
+)
+
+const maxImageRows = 20
+
+var reImage = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+
+// detectGraphics guesses which inline image protocol the terminal speaks.
+// Kitty and iTerm2 are known by their environment; SIXEL by TERM or, when
+// probe is set and stdout is a terminal, by asking the terminal.
+func detectGraphics(probe bool) graphicsProto {
+	termVar := os.Getenv("TERM")
+	if strings.Contains(termVar, "kitty") || os.Getenv("KITTY_WINDOW_ID") != "" {
+		return graphicsKitty
+	}
+	switch os.Getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm":
+		return graphicsITerm
+	}
+	for _, t := range []string{"mlterm", "foot", "yaft"} {
+		if strings.HasPrefix(termVar, t) {
+			return graphicsSixel
+		}
+	}
+	if probe && isatty.IsTerminal(os.Stdout.Fd()) && querySixel(probeTimeout) {
+		return graphicsSixel
+	}
+	return graphicsNone
+}
+
+// querySixel reports whether the terminal lists SIXEL graphics (4) among
+// its primary device attributes; xterm only does when built and run with
+// it, so TERM alone can't tell.
+func querySixel(timeout time.Duration) bool {
+	da := reDA1.Find(queryTerminal("", timeout))
+	if da == nil {
+		return false
+	}
+	for _, p := range strings.Split(string(da[3:len(da)-1]), ";") {
+		if p == "4" {
+			return true
+		}
+	}
+	return false
+}
+
+// inlineImage is a local image swapped out of the Markdown before rendering.
+type inlineImage struct {
+	placeholder string
+	path        string
+	alt         string
+}
+
+func imagePlaceholder(i int) string { return fmt.Sprintf("MDNFOIMAGE%04dX", i) }
+
+// extractImages replaces displayable local images in raw with placeholder
+// paragraphs; everything else becomes its alt text.
+func extractImages(raw, baseDir string, proto graphicsProto) (string, []inlineImage) {
+	var imgs []inlineImage
+	out := reImage.ReplaceAllStringFunc(raw, func(s string) string {
+		mm := reImage.FindStringSubmatch(s)
+		alt, src := mm[1], mm[2]
+		fallback := "[image: " + alt + "]"
+		if alt == "" {
+			fallback = "[image]"
+		}
+		if proto == graphicsNone || strings.Contains(src, "://") {
+			return fallback
+		}
+		path := src
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fallback
+		}
+		img := inlineImage{placeholder: imagePlaceholder(len(imgs)), path: path, alt: fallback}
+		imgs = append(imgs, img)
+		return "\n\n" + img.placeholder + "\n\n"
+	})
+	return out, imgs
+}
+
+// injectImages swaps each placeholder line in the rendered output for the
+// graphics escape, followed by blank lines reserving the image's rows.
+func injectImages(rendered string, imgs []inlineImage, proto graphicsProto, width int) string {
+	if len(imgs) == 0 {
+		return rendered
+	}
+	lines := strings.Split(rendered, "\n")
+	var out []string
+	for _, line := range lines {
+		plain := stripANSI(line)
+		var hit *inlineImage
+		for i := range imgs {
+			if strings.Contains(plain, imgs[i].placeholder) {
+				hit = &imgs[i]
+				break
+			}
+		}
+		if hit == nil {
+			out = append(out, line)
+			continue
+		}
+		indent := plain[:len(plain)-len(strings.TrimLeft(plain, " "))]
+		seq, rows, err := imageEscape(hit.path, proto, max(1, width-2*len(indent)))
+		if err != nil {
+			out = append(out, indent+hit.alt)
+			continue
+		}
+		out = append(out, indent+seq)
+		for r := 1; r < rows; r++ {
+			out = append(out, "")
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// imageEscape encodes the image at path for proto, sized to at most cols
+// columns, and reports how many rows it occupies.
+func imageEscape(path string, proto graphicsProto, cols int) (seq string, rows int, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", 0, err
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", 0, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return "", 0, fmt.Errorf("%s: empty image", path)
+	}
+
+	// assume cells are roughly twice as tall as they are wide
+	cols = clamp(cfg.Width/8, 1, cols)
+	rows = clamp(cols*cfg.Height/cfg.Width/2, 1, maxImageRows)
+
+	switch proto {
+	case graphicsKitty:
+		if format != "png" {
+			// kitty's f=100 wants PNG; re-encode anything else
+			img, _, err := image.Decode(bytes.NewReader(data))
+			if err != nil {
+				return "", 0, err
+			}
+			var buf bytes.Buffer
+			if err := png.Encode(&buf, img); err != nil {
+				return "", 0, err
+			}
+			data = buf.Bytes()
+		}
+		return kittyEscape(data, cols, rows), rows, nil
+	case graphicsITerm:
+		b64 := base64.StdEncoding.EncodeToString(data)
+		return fmt.Sprintf("\x1b]1337;File=inline=1;width=%d;height=%d;preserveAspectRatio=1:%s\x07", cols, rows, b64), rows, nil
+	case graphicsSixel:
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return "", 0, err
+		}
+		// SIXEL draws pixels, not cells: fit the image in cols x rows
+		pw := cols * sixelCellWidth
+		ph := max(1, pw*cfg.Height/cfg.Width)
+		if ph > rows*sixelCellHeight {
+			ph = rows * sixelCellHeight
+			pw = max(1, ph*cfg.Width/cfg.Height)
+		}
+		return sixelEscape(img, pw, ph), (ph + sixelCellHeight - 1) / sixelCellHeight, nil
+	}
+	return "", 0, fmt.Errorf("no graphics protocol")
+}
+
+// kittyEscape transmits and displays PNG data in 4 KiB chunks. q=2 keeps
+// the terminal from answering on stdin; C=1 leaves the cursor in place.
+func kittyEscape(data []byte, cols, rows int) string {
+	b64 := base64.StdEncoding.EncodeToString(data)
+	var b strings.Builder
+	for first := true; len(b64) > 0; first = false {
+		n := min(4096, len(b64))
+		chunk := b64[:n]
+		b64 = b64[n:]
+		more := 0
+		if len(b64) > 0 {
+			more = 1
+		}
+		if first {
+			fmt.Fprintf(&b, "\x1b_Ga=T,f=100,q=2,C=1,c=%d,r=%d,m=%d;%s\x1b\\", cols, rows, more, chunk)
+		} else {
+			fmt.Fprintf(&b, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+	return b.String()
+}
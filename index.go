@@ -0,0 +1,408 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"mdnfo/render"
+)
+
+// ---------- link & heading helpers ----------
+
+type link struct {
+	text         string
+	target       string // url or #anchor
+	renderedLine int
+	source       int // byte offset in the Markdown
+}
+
+type heading struct {
+	level        int // 1-6
+	text         string
+	anchor       string // github-style slug
+	line         int    // source line it starts on, from 0
+	renderedLine int
+	term         bool // --nav-defs: a definition term or bold line, no anchor
+}
+
+func slugify(s string) string {
+	s = strings.ToLower(s)
+	var b strings.Builder
+	for _, r := range s {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == ' ' || r == '-' {
+			b.WriteRune(r)
+		}
+	}
+	return strings.Trim(strings.Join(strings.Fields(strings.ReplaceAll(b.String(), " ", "-")), "-"), "-")
+}
+
+// ANSI: SGR sequences, OSC strings (hyperlinks, iTerm2 images), and the
+// other inline image escapes, all zero-width
+var ansiRE = render.ANSI
+
+func stripANSI(s string) string { return render.StripANSI(s) }
+
+var (
+	reHeading = regexp.MustCompile(`^\s{0,3}(#{1,6})\s+(.*)$`)
+	reSetext  = regexp.MustCompile(`^\s{0,3}(=+|-+)\s*$`)
+	reRule    = regexp.MustCompile(`^\s{0,3}((\*\s*){3,}|(-\s*){3,}|(_\s*){3,})$`)
+	reBlock   = regexp.MustCompile(`^(\s{4,}|\s{0,3}([>|<]|[-*+]\s|\d+[.)]\s))`)
+	reDefDesc = regexp.MustCompile(`^\s{0,3}:\s+\S`)
+	reBoldRow = regexp.MustCompile(`^(?:\*\*([^*]+)\*\*|__([^_]+)__):?$`)
+	reLink    = regexp.MustCompile(`\[(?P<text>[^\]]+)\]\((?P<dest>[^)]+)\)`)
+
+	// reference-style links and footnotes
+	reRefDef      = regexp.MustCompile(`(?m)^\s{0,3}\[([^\]^][^\]]*)\]:[ \t]*<?([^\s>]+)>?`)
+	reRefLink     = regexp.MustCompile(`\[([^\[\]]+)\]\[([^\[\]]*)\]`)
+	reShortRef    = regexp.MustCompile(`\[([^\[\]^][^\[\]]*)\]`)
+	reFootnoteDef = regexp.MustCompile(`(?m)^\s{0,3}\[\^([^\]]+)\]:`)
+	reFootnoteRef = regexp.MustCompile(`\[\^([^\]]+)\]`)
+
+	// bare URLs, linked by GFM but not by every renderer
+	reBareURL = regexp.MustCompile("(?:https?://|www\\.)[^\\s<>`]+")
+)
+
+// ---------- indexing (restored) ----------
+
+func (m *model) buildIndexes() {
+	// Build indexes from the CURRENT visible content (post-effects stripped),
+	// so anchors/links scroll to what the user actually sees right now.
+	plain := stripANSI(strings.Join(m.renderedLines, "\n"))
+
+	// headings appear in source order, so each is looked for after the
+	// previous one; repeated titles then land on their own lines
+	m.headings = parseHeadings(m.markdown(), m.navDefs)
+	loc := newTextLocator(plain)
+	for i := range m.headings {
+		m.headings[i].renderedLine = loc.next(m.headings[i].text)
+	}
+
+	m.sourceMap = m.buildSourceMap()
+
+	m.links, m.footnotes = parseLinks(m.markdown(), plain)
+	if m.editTasks {
+		m.tasks = parseTasks(m.markdown(), plain)
+		if m.taskIndex >= len(m.tasks) {
+			m.taskIndex = len(m.tasks) - 1
+		}
+	}
+	if len(m.links) == 0 {
+		m.linkIndex = -1
+	} else if m.linkIndex >= len(m.links) {
+		m.linkIndex = len(m.links) - 1
+	}
+	if m.diffName != "" {
+		m.diffHunks = diffHunks(strings.Split(plain, "\n"))
+	}
+}
+
+// parseLinks finds inline, reference, footnote and bare URL links in raw,
+// in source order, locating each in the rendered plain text. footnotes
+// maps each footnote label to the rendered line of its definition.
+func parseLinks(raw, plain string) (links []link, footnotes map[string]int) {
+	var taken [][]int // source spans already claimed by a link or definition
+
+	// reference definitions: [label]: url
+	refs := map[string]string{}
+	for _, mm := range reRefDef.FindAllStringSubmatchIndex(raw, -1) {
+		key := refKey(raw[mm[2]:mm[3]])
+		if _, dup := refs[key]; !dup {
+			refs[key] = raw[mm[4]:mm[5]]
+		}
+		taken = append(taken, mm[:2])
+	}
+
+	// footnote definitions: [^label]: text
+	footnotes = map[string]int{}
+	for _, mm := range reFootnoteDef.FindAllStringSubmatchIndex(raw, -1) {
+		label := raw[mm[2]:mm[3]]
+		footnotes[label] = indexLineOf(plain, "[^"+label+"]:")
+		taken = append(taken, mm[:2])
+	}
+
+	type sourceLink struct {
+		pos int
+		l   link
+	}
+	var found []sourceLink
+	add := func(span []int, text, dest string) {
+		found = append(found, sourceLink{pos: span[0], l: link{text: text, target: dest}})
+		taken = append(taken, span[:2])
+	}
+
+	// inline: [text](dest)
+	for _, mm := range reLink.FindAllStringSubmatchIndex(raw, -1) {
+		add(mm, raw[mm[2]:mm[3]], raw[mm[4]:mm[5]])
+	}
+	// full and collapsed reference: [text][ref], [text][]
+	for _, mm := range reRefLink.FindAllStringSubmatchIndex(raw, -1) {
+		if overlapsAny(taken, mm) {
+			continue
+		}
+		text, label := raw[mm[2]:mm[3]], raw[mm[4]:mm[5]]
+		if label == "" {
+			label = text
+		}
+		if dest, ok := refs[refKey(label)]; ok {
+			add(mm, text, dest)
+		}
+	}
+	// footnote references: [^label] -> #fn-label
+	for _, mm := range reFootnoteRef.FindAllStringSubmatchIndex(raw, -1) {
+		if overlapsAny(taken, mm) {
+			continue
+		}
+		label := raw[mm[2]:mm[3]]
+		if _, ok := footnotes[label]; ok {
+			add(mm, "[^"+label+"]", "#fn-"+label)
+		}
+	}
+	// bare URLs: https://example.org, www.example.org
+	fences := fencedSpans(raw)
+	for _, mm := range reBareURL.FindAllStringIndex(raw, -1) {
+		mm[1] = mm[0] + len(trimURL(raw[mm[0]:mm[1]]))
+		if overlapsAny(taken, mm) || overlapsAny(fences, mm) || (mm[0] > 0 && isURLChar(raw[mm[0]-1])) {
+			continue
+		}
+		url := raw[mm[0]:mm[1]]
+		dest := url
+		if strings.HasPrefix(url, "www.") {
+			dest = "http://" + url
+		}
+		add(mm, url, dest)
+	}
+	// shortcut reference: [ref]
+	for _, mm := range reShortRef.FindAllStringSubmatchIndex(raw, -1) {
+		if overlapsAny(taken, mm) || (mm[1] < len(raw) && strings.ContainsRune("([:", rune(raw[mm[1]]))) {
+			continue
+		}
+		text := raw[mm[2]:mm[3]]
+		if dest, ok := refs[refKey(text)]; ok {
+			add(mm, text, dest)
+		}
+	}
+
+	// located in source order, so a repeated URL or link text maps to
+	// its own occurrence rather than the first
+	sort.SliceStable(found, func(i, j int) bool { return found[i].pos < found[j].pos })
+	loc := newTextLocator(plain)
+	for _, f := range found {
+		needle := f.l.target
+		if strings.HasPrefix(needle, "#") {
+			needle = f.l.text
+		}
+		f.l.renderedLine = loc.next(needle)
+		f.l.source = f.pos
+		links = append(links, f.l)
+	}
+	return links, footnotes
+}
+
+// parseHeadings extracts the document's headings in order; renderedLine is
+// left at -1 for the caller to fill in. Repeated titles get GitHub's -1, -2
+// anchor suffixes, the same ids the HTML export uses. With terms, the
+// terms of definition lists and paragraphs that are a bold line alone are
+// listed too, a level below the heading they follow and without anchors.
+func parseHeadings(raw string, terms bool) []heading {
+	var out []heading
+	ids := &slugIDs{seen: map[string]bool{}}
+	last := 0 // level of the last real heading
+	add := func(level int, txt string, line int) {
+		if txt = strings.TrimSpace(txt); txt != "" {
+			out = append(out, heading{level: level, text: txt, anchor: ids.next(txt), line: line, renderedLine: -1})
+			last = level
+		}
+	}
+	addTerm := func(txt string, line int) {
+		if txt = strings.TrimSpace(strings.TrimSuffix(txt, ":")); txt != "" {
+			out = append(out, heading{level: min(last+1, 6), text: txt, line: line, renderedLine: -1, term: true})
+		}
+	}
+	lines := strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n")
+	start := 0
+	if len(lines) > 0 && lines[0] == "---" {
+		// YAML front matter: its closing --- would read as an underline
+		for i := 1; i < len(lines); i++ {
+			if lines[i] == "---" || lines[i] == "..." {
+				start = i + 1
+				break
+			}
+		}
+	}
+	fenced, inBlock := false, false // inBlock: in a list item, quote, table...
+	var para []string               // the open paragraph: a setext heading's text
+	for i, line := range lines[start:] {
+		n := start + i // source line
+		t := strings.TrimSpace(line)
+		if strings.HasPrefix(t, "```") || strings.HasPrefix(t, "~~~") {
+			fenced, para = !fenced, nil
+			continue
+		}
+		if fenced {
+			continue
+		}
+		if mm := reHeading.FindStringSubmatch(line); mm != nil {
+			add(len(mm[1]), mm[2], n)
+			para = nil
+			continue
+		}
+		// an underline only makes a heading of a paragraph; after a blank
+		// line, a list item or a quote, --- is a rule
+		if mm := reSetext.FindStringSubmatch(line); mm != nil && len(para) > 0 {
+			level := 1
+			if mm[1][0] == '-' {
+				level = 2
+			}
+			add(level, strings.Join(para, " "), n-len(para))
+			para = nil
+			continue
+		}
+		if terms && !inBlock {
+			if len(para) > 0 && reDefDesc.MatchString(line) {
+				// the terms are the paragraph's lines since the last ": "
+				first := len(para)
+				for first > 0 && !reDefDesc.MatchString(para[first-1]) {
+					first--
+				}
+				for k, term := range para[first:] {
+					addTerm(term, n-len(para)+first+k)
+				}
+			} else if mm := reBoldRow.FindStringSubmatch(t); mm != nil && len(para) == 0 {
+				addTerm(mm[1]+mm[2], n)
+			}
+		}
+		switch {
+		case t == "" || reRule.MatchString(line):
+			para, inBlock = nil, false
+		case len(para) == 0 && reBlock.MatchString(line):
+			inBlock = true
+		case !inBlock:
+			para = append(para, t)
+		}
+	}
+	return out
+}
+
+// trimURL drops the punctuation that ends a sentence rather than a bare
+// URL, and a closing parenthesis with no opening one in the URL, as GFM
+// autolinks do.
+func trimURL(u string) string {
+	for u != "" {
+		c := u[len(u)-1]
+		switch {
+		case strings.IndexByte(`?!.,:;*_~'"`, c) >= 0:
+		case c == ')' && strings.Count(u, ")") > strings.Count(u, "("):
+		default:
+			return u
+		}
+		u = u[:len(u)-1]
+	}
+	return u
+}
+
+// isURLChar reports whether c can run into a URL, so "xhttps://" or
+// "ftp.www.x" aren't taken for bare URLs.
+func isURLChar(c byte) bool {
+	return c == '.' || c == '/' || c == '-' || c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// fencedSpans returns the byte spans of raw's fenced code blocks.
+func fencedSpans(raw string) [][]int {
+	var spans [][]int
+	open, off := -1, 0
+	for _, line := range strings.SplitAfter(raw, "\n") {
+		if t := strings.TrimSpace(line); strings.HasPrefix(t, "```") || strings.HasPrefix(t, "~~~") {
+			if open < 0 {
+				open = off
+			} else {
+				spans = append(spans, []int{open, off + len(line)})
+				open = -1
+			}
+		}
+		off += len(line)
+	}
+	if open >= 0 {
+		spans = append(spans, []int{open, len(raw)})
+	}
+	return spans
+}
+
+// refKey normalizes a reference label: case-insensitive, collapsed spaces.
+func refKey(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+func overlapsAny(spans [][]int, span []int) bool {
+	for _, s := range spans {
+		if span[0] < s[1] && s[0] < span[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// textLocator finds strings in rendered plain text in document order. Each
+// search starts after the previous match, so repeated text maps to its own
+// occurrence, and whitespace is ignored, so text glamour wrapped (or broke
+// mid-word) across lines still matches.
+type textLocator struct {
+	plain  string
+	packed string // plain without whitespace
+	offs   []int  // offset in plain of each byte of packed
+	pos    int    // offset in packed the next search starts at
+}
+
+func newTextLocator(plain string) *textLocator {
+	var b strings.Builder
+	var offs []int
+	for i := 0; i < len(plain); {
+		r, n := utf8.DecodeRuneInString(plain[i:])
+		if !unicode.IsSpace(r) {
+			b.WriteString(plain[i : i+n])
+			for k := range n {
+				offs = append(offs, i+k)
+			}
+		}
+		i += n
+	}
+	return &textLocator{plain: plain, packed: b.String(), offs: offs}
+}
+
+// next returns the line of needle's first occurrence after the previous
+// match, or of its first occurrence anywhere if there is none after it;
+// -1 when it doesn't occur at all.
+func (l *textLocator) next(needle string) int {
+	n := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, needle)
+	if n == "" {
+		return -1
+	}
+	if rel := strings.Index(l.packed[l.pos:], n); rel >= 0 {
+		at := l.pos + rel
+		l.pos = at + len(n)
+		return strings.Count(l.plain[:l.offs[at]], "\n")
+	}
+	if at := strings.Index(l.packed, n); at >= 0 {
+		return strings.Count(l.plain[:l.offs[at]], "\n")
+	}
+	return -1
+}
+
+func indexLineOf(haystack, needle string) int {
+	if needle == "" {
+		return -1
+	}
+	pos := strings.Index(haystack, needle)
+	if pos < 0 {
+		return -1
+	}
+	return bytes.Count([]byte(haystack[:pos]), []byte("\n"))
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// linksDoc mixes inline, full, collapsed and shortcut reference, footnote
+// and bare URL links; linksPlain is how glamour renders it.
+const linksDoc = `# Links
+
+See [the docs](https://example.com/docs) and [the spec][spec].
+Also [Go][] and plain [go] here.[^1]
+
+More at https://example.org/more.
+
+[spec]: https://example.com/spec
+[go]: https://go.dev
+
+[^1]: A footnote.
+`
+
+const linksPlain = `
+  # Links
+
+  See the docs https://example.com/docs and the spec https://example.com/spec.
+  Also Go https://go.dev and plain go https://go.dev here.[^1]
+
+  More at https://example.org/more.
+
+  [^1]: A footnote.
+`
+
+func TestParseLinks(t *testing.T) {
+	links, footnotes := parseLinks(linksDoc, linksPlain)
+	type want struct {
+		text, target string
+		line         int
+	}
+	wants := []want{
+		{"the docs", "https://example.com/docs", 3},
+		{"the spec", "https://example.com/spec", 3},
+		{"Go", "https://go.dev", 4},
+		{"go", "https://go.dev", 4},
+		{"[^1]", "#fn-1", 4},
+		{"https://example.org/more", "https://example.org/more", 6},
+	}
+	var got []want
+	for _, l := range links {
+		got = append(got, want{l.text, l.target, l.renderedLine})
+	}
+	if !reflect.DeepEqual(got, wants) {
+		t.Errorf("links:\n got %+v\nwant %+v", got, wants)
+	}
+	for i := 1; i < len(links); i++ {
+		if links[i].source <= links[i-1].source {
+			t.Errorf("links out of source order at %d: %+v", i, links)
+		}
+	}
+	if want := map[string]int{"1": 8}; !reflect.DeepEqual(footnotes, want) {
+		t.Errorf("footnotes = %v, want %v", footnotes, want)
+	}
+}
+
+func TestParseLinksUndefinedRef(t *testing.T) {
+	links, _ := parseLinks("[text][nowhere] and [alone] and [^none]\n", "text and alone and [^none]\n")
+	if len(links) != 0 {
+		t.Errorf("links to undefined references: %+v", links)
+	}
+}
+
+// dupDoc has three headings titled alike, which GitHub tells apart as
+// setup, setup-1 and setup-2.
+const dupDoc = `# Guide
+
+## Setup
+
+first
+
+## Setup
+
+second
+
+## Setup
+
+third
+`
+
+func TestParseHeadingsDuplicates(t *testing.T) {
+	var got []string
+	for _, h := range parseHeadings(dupDoc, false) {
+		got = append(got, h.anchor)
+	}
+	want := []string{"guide", "setup", "setup-1", "setup-2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("anchors = %q, want %q", got, want)
+	}
+}
+
+func TestParseHeadingsSetext(t *testing.T) {
+	doc := `Title
+=====
+
+Intro text.
+
+## ATX section
+
+Sub
+---
+
+Some text
+followed by a rule
+
+---
+
+| a | b |
+|---|---|
+| 1 | 2 |
+
+- item
+---
+
+    code
+    ---
+
+Last
+===
+`
+	type want struct {
+		level int
+		text  string
+		line  int
+	}
+	var got []want
+	for _, h := range parseHeadings(doc, false) {
+		got = append(got, want{h.level, h.text, h.line})
+	}
+	wants := []want{
+		{1, "Title", 0},
+		{2, "ATX section", 5},
+		{2, "Sub", 7},
+		{1, "Last", 25},
+	}
+	if !reflect.DeepEqual(got, wants) {
+		t.Errorf("headings:\n got %+v\nwant %+v", got, wants)
+	}
+}
+
+func TestTextLocator(t *testing.T) {
+	plain := "see the docs here\nand the docs there\n\nthe long link te\nxt wraps\nthe docs"
+	loc := newTextLocator(plain)
+	tests := []struct {
+		needle string
+		want   int
+	}{
+		{"the docs", 0},
+		{"the docs", 1},           // the second occurrence, not the first again
+		{"the long link text", 3}, // wrapped, and broken mid-word
+		{"the docs", 5},
+		{"the docs", 0}, // none left after: the first anywhere
+		{"missing", -1},
+		{"  ", -1},
+	}
+	for i, tt := range tests {
+		if got := loc.next(tt.needle); got != tt.want {
+			t.Errorf("%d: next(%q) = %d, want %d", i, tt.needle, got, tt.want)
+		}
+	}
+}
+
+func TestParseLinksRepeatedText(t *testing.T) {
+	raw := "# Repeats\n\n[here](#a) and [here](#b).\n\nMore text, then [here](#c)\nand a [very long link text that wraps](#d).\n"
+	plain := "\n  # Repeats\n\n  here and here.\n\n  More text, then here and a very long link\n  text that wraps.\n"
+	links, _ := parseLinks(raw, plain)
+	var got []int
+	for _, l := range links {
+		got = append(got, l.renderedLine)
+	}
+	if want := []int{3, 3, 5, 5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("lines = %v, want %v", got, want)
+	}
+	if len(links) == 4 && links[2].target != "#c" {
+		t.Errorf("third link is %+v, want #c", links[2])
+	}
+}
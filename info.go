@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ---------- document info panel (i) ----------
+
+const wordsPerMinute = 200
+
+var (
+	reFence      = regexp.MustCompile("(?m)^\\s*(```|~~~)")
+	reInlineLink = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	reHTMLTag    = regexp.MustCompile(`<[^>]+>`)
+)
+
+// countWords roughly counts the prose words of a Markdown document: fence
+// markers, link URLs and HTML tags are dropped, and only tokens containing
+// a letter or digit count.
+func countWords(raw string) int {
+	s := reFence.ReplaceAllString(raw, "")
+	s = reInlineLink.ReplaceAllString(s, "$1")
+	s = reHTMLTag.ReplaceAllString(s, " ")
+	n := 0
+	for _, f := range strings.Fields(s) {
+		if strings.IndexFunc(f, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// readingTime estimates minutes at wordsPerMinute, at least one.
+func readingTime(words int) int {
+	return max(1, (words+wordsPerMinute-1)/wordsPerMinute)
+}
+
+func (m *model) updateInfo(msg tea.KeyMsg) {
+	if msg.Type == tea.KeyEsc || msg.String() == "i" {
+		m.mode = modeNormal
+	}
+}
+
+func (m *model) infoOverlay(body string, width int) string {
+	headings := 0
+	for _, h := range m.headings {
+		if !h.term {
+			headings++
+		}
+	}
+	lines := []string{
+		"File      " + m.filename,
+		"Size      " + humanSize(m.fileSize),
+		"Modified  " + m.fileMod.Format(time.RFC3339),
+		fmt.Sprintf("Words     %d", m.words),
+		fmt.Sprintf("Reading   ~%d min", readingTime(m.words)),
+		fmt.Sprintf("Headings  %d", headings),
+		fmt.Sprintf("Links     %d", len(m.links)),
+	}
+	if m.frontMatter == frontMatterShow {
+		if fields := frontMatterFields(m.rawMarkdown); len(fields) > 0 {
+			lines = append(lines, "", "Front matter")
+			for _, f := range fields {
+				lines = append(lines, fmt.Sprintf("  %-8s %s", f.key, f.value))
+			}
+		}
+	}
+	inner := 0
+	for _, l := range lines {
+		inner = max(inner, displayWidth(l))
+	}
+	inner = min(inner, max(1, width-6))
+	return placeOverlay(body, drawBox(" Info ", lines, inner, -1), width)
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ---------- key actions ----------
+
+type action string
+
+const (
+	actScrollUp        action = "scroll-up"
+	actScrollDown      action = "scroll-down"
+	actPageUp          action = "page-up"
+	actPageDown        action = "page-down"
+	actHalfPageUp      action = "half-page-up"
+	actHalfPageDown    action = "half-page-down"
+	actTop             action = "top"
+	actBottom          action = "bottom"
+	actNextLink        action = "next-link"
+	actPrevLink        action = "prev-link"
+	actFollowLink      action = "follow-link"
+	actCopyLink        action = "copy-link"
+	actHighlightLinks  action = "toggle-link-highlight"
+	actSearch          action = "search"
+	actNextMatch       action = "next-match"
+	actPrevMatch       action = "prev-match"
+	actGotoLine        action = "goto-line"
+	actSetBookmark     action = "set-bookmark"
+	actJumpBookmark    action = "jump-bookmark"
+	actToc             action = "toc"
+	actHelp            action = "help"
+	actInfo            action = "info"
+	actNextTask        action = "next-task"
+	actPrevTask        action = "prev-task"
+	actNextChange      action = "next-change"
+	actPrevChange      action = "prev-change"
+	actNextFile        action = "next-file"
+	actPrevFile        action = "prev-file"
+	actBack            action = "back"
+	actForward         action = "forward"
+	actPause           action = "pause"
+	actSkipStream      action = "skip-stream"
+	actBaud300         action = "baud-300"
+	actBaud1200        action = "baud-1200"
+	actBaud9600        action = "baud-9600"
+	actBaud57600       action = "baud-57600"
+	actBaud115200      action = "baud-115200"
+	actToggleScanlines action = "toggle-scanlines"
+	actToggleMono      action = "toggle-mono"
+	actInverse         action = "toggle-inverse"
+	actToggleBBS       action = "toggle-bbs"
+	actDegauss         action = "degauss"
+	actToggleCursor    action = "toggle-cursor"
+	actWrapWider       action = "wrap-wider"
+	actWrapNarrower    action = "wrap-narrower"
+	actWrapMode        action = "wrap-mode"
+	actCycleStyle      action = "cycle-style"
+	actEdit            action = "edit"
+	actReload          action = "reload"
+	actRedraw          action = "redraw"
+	actAutoscroll      action = "toggle-autoscroll"
+	actMinimal         action = "toggle-minimal"
+	actLineNumbers     action = "toggle-line-numbers"
+	actDimRead         action = "toggle-dim-read"
+	actPanLeft         action = "pan-left"
+	actPanRight        action = "pan-right"
+	actQuit            action = "quit"
+)
+
+// baudPresets are the rates behind the baud-* actions.
+var baudPresets = map[action]int{
+	actBaud300:    300,
+	actBaud1200:   1200,
+	actBaud9600:   9600,
+	actBaud57600:  57600,
+	actBaud115200: 115200,
+}
+
+type binding struct {
+	action action
+	keys   []string // as reported by tea.KeyMsg.String()
+	desc   string
+}
+
+// defaultBindings is the source of truth for actions, their default keys
+// and their help text, in display order.
+var defaultBindings = []binding{
+	{actScrollUp, []string{"up", "k"}, "scroll up one line"},
+	{actScrollDown, []string{"down", "j"}, "scroll down one line"},
+	{actPageUp, []string{"pgup", "ctrl+b"}, "scroll up one page"},
+	{actPageDown, []string{"pgdown", "ctrl+f"}, "scroll down one page"},
+	{actHalfPageUp, []string{"ctrl+u"}, "scroll up half a page"},
+	{actHalfPageDown, []string{"ctrl+d"}, "scroll down half a page"},
+	{actTop, []string{"home", "g"}, "jump to first line"},
+	{actBottom, []string{"end", "G"}, "jump to last line"},
+	{actNextLink, []string{"tab"}, "select next link"},
+	{actPrevLink, []string{"shift+tab"}, "select previous link"},
+	{actFollowLink, []string{"enter"}, "follow selected link (or skip stream)"},
+	{actCopyLink, []string{"y"}, "copy selected link's URL"},
+	{actHighlightLinks, []string{"L"}, "highlight all links"},
+	{actSearch, []string{"/"}, "search"},
+	{actNextMatch, []string{"n"}, "next match"},
+	{actPrevMatch, []string{"N"}, "previous match"},
+	{actGotoLine, []string{":"}, "jump to line or percent (e.g. 120, 50%)"},
+	{actSetBookmark, []string{"M"}, "set bookmark 0-9 here (then a digit)"},
+	{actJumpBookmark, []string{"'"}, "jump to bookmark 0-9 (then a digit)"},
+	{actToc, []string{"t"}, "table of contents"},
+	{actHelp, []string{"?"}, "show this help"},
+	{actInfo, []string{"i"}, "document info (words, reading time)"},
+	{actNextTask, []string{"}"}, "select next task (--edit-tasks; Space toggles)"},
+	{actPrevTask, []string{"{"}, "select previous task"},
+	{actNextChange, []string{")"}, "next change (--diff)"},
+	{actPrevChange, []string{"("}, "previous change"},
+	{actNextFile, []string{"]"}, "next file"},
+	{actPrevFile, []string{"["}, "previous file"},
+	{actBack, []string{"backspace", "alt+left"}, "back to the previous location"},
+	{actForward, []string{"alt+right"}, "forward again"},
+	{actPause, []string{" "}, "pause / resume streaming"},
+	{actSkipStream, []string{"f"}, "skip to full text"},
+	{actBaud300, []string{"1"}, "redial at 300 baud"},
+	{actBaud1200, []string{"2"}, "redial at 1200 baud"},
+	{actBaud9600, []string{"3"}, "redial at 9600 baud"},
+	{actBaud57600, []string{"4"}, "redial at 57600 baud"},
+	{actBaud115200, []string{"5"}, "redial at 115200 baud"},
+	{actToggleScanlines, []string{"s"}, "toggle scanlines"},
+	{actToggleMono, []string{"m"}, "cycle mono mode"},
+	{actInverse, []string{"v"}, "toggle reverse video"},
+	{actToggleBBS, []string{"b"}, "toggle BBS status line"},
+	{actDegauss, []string{"d"}, "degauss"},
+	{actToggleCursor, []string{"c"}, "toggle blinking cursor"},
+	{actWrapWider, []string{"+", "=", ">"}, "widen wrap width"},
+	{actWrapNarrower, []string{"-", "<"}, "narrow wrap width"},
+	{actWrapMode, []string{"w"}, "cycle wrap mode: word, char, none"},
+	{actCycleStyle, []string{"T"}, "cycle styles to preview them"},
+	{actEdit, []string{"e"}, "open $EDITOR at this line of the source"},
+	{actReload, []string{"r"}, "reload the file from disk"},
+	{actRedraw, []string{"ctrl+l"}, "redraw the whole screen"},
+	{actAutoscroll, []string{"a"}, "auto-scroll on / off (scrolling by hand pauses it)"},
+	{actMinimal, []string{"z"}, "hide / show header and footer"},
+	{actLineNumbers, []string{"l"}, "toggle line numbers"},
+	{actDimRead, []string{"D"}, "dim what you have read"},
+	{actPanLeft, []string{"shift+left", "left"}, "scroll left"},
+	{actPanRight, []string{"shift+right", "right"}, "scroll right"},
+	{actQuit, []string{"q", "esc", "ctrl+c"}, "quit"},
+}
+
+// keymap resolves tea key strings to actions.
+type keymap map[string]action
+
+func defaultKeymap() keymap {
+	km, _ := newKeymap(nil)
+	return km
+}
+
+// newKeymap builds a keymap from the defaults, replacing the keys of any
+// action named in overrides. Overridden keys win over default ones.
+func newKeymap(overrides map[string][]string) (keymap, error) {
+	known := map[action]bool{}
+	for _, b := range defaultBindings {
+		known[b.action] = true
+	}
+	for name := range overrides {
+		if !known[action(name)] {
+			return nil, fmt.Errorf("unknown key action %q", name)
+		}
+	}
+
+	km := keymap{}
+	for _, b := range defaultBindings {
+		if _, ok := overrides[string(b.action)]; !ok {
+			for _, k := range b.keys {
+				km[k] = b.action
+			}
+		}
+	}
+	for _, b := range defaultBindings {
+		for _, k := range overrides[string(b.action)] {
+			km[normalizeKey(k)] = b.action
+		}
+	}
+	return km, nil
+}
+
+// lookup finds the action for a key; single letters fall back to their
+// lowercase binding so toggles work with Shift or Caps Lock.
+func (km keymap) lookup(key string) action {
+	if a, ok := km[key]; ok {
+		return a
+	}
+	return km[strings.ToLower(key)]
+}
+
+// normalizeKey maps friendly config names onto tea key strings.
+func normalizeKey(k string) string {
+	switch strings.ToLower(k) {
+	case "space":
+		return " "
+	case "escape":
+		return "esc"
+	case "return":
+		return "enter"
+	case "pageup":
+		return "pgup"
+	case "pagedown":
+		return "pgdown"
+	}
+	return k
+}
+
+// keysFor lists the keys bound to a, defaults first in their usual order.
+func (km keymap) keysFor(a action) []string {
+	rank := map[string]int{}
+	for _, b := range defaultBindings {
+		if b.action == a {
+			for i, k := range b.keys {
+				rank[k] = i + 1
+			}
+		}
+	}
+	var keys []string
+	for k, ka := range km {
+		if ka == a {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ri, rj := rank[keys[i]], rank[keys[j]]
+		if ri == 0 || rj == 0 || ri == rj {
+			if (ri == 0) != (rj == 0) {
+				return ri != 0
+			}
+			return keys[i] < keys[j]
+		}
+		return ri < rj
+	})
+	return keys
+}
+
+// helpLines describes every action with the keys km currently binds it to.
+func (km keymap) helpLines() []string {
+	type row struct{ keys, desc string }
+	rows := make([]row, 0, len(defaultBindings))
+	width := 0
+	for _, b := range defaultBindings {
+		keys := km.keysFor(b.action)
+		labels := make([]string, len(keys))
+		for i, k := range keys {
+			labels[i] = keyLabel(k)
+		}
+		r := row{strings.Join(labels, ", "), b.desc}
+		if r.keys == "" {
+			r.keys = "(unbound)"
+		}
+		width = max(width, len(r.keys))
+		rows = append(rows, r)
+	}
+	lines := make([]string, len(rows))
+	for i, r := range rows {
+		lines[i] = fmt.Sprintf("%-*s  %s", width, r.keys, r.desc)
+	}
+	return lines
+}
+
+func keyLabel(k string) string {
+	if k == " " {
+		return "space"
+	}
+	return k
+}
+
+// keyHelp lists every action with its default keys, for --help.
+func keyHelp() string {
+	keys := make([]string, len(defaultBindings))
+	actionWidth, keysWidth := 0, 0
+	for i, bd := range defaultBindings {
+		labels := make([]string, len(bd.keys))
+		for j, k := range bd.keys {
+			labels[j] = keyLabel(k)
+		}
+		keys[i] = strings.Join(labels, ", ")
+		actionWidth = max(actionWidth, len(bd.action))
+		keysWidth = max(keysWidth, len(keys[i]))
+	}
+	var b strings.Builder
+	for i, bd := range defaultBindings {
+		fmt.Fprintf(&b, "  %-*s %-*s %s\n", actionWidth, bd.action, keysWidth, keys[i], bd.desc)
+	}
+	return b.String()
+}
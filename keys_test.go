@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestKeyHelpAligned checks every --help key row starts its key and
+// description columns at the same place, however long the action name.
+func TestKeyHelpAligned(t *testing.T) {
+	lines := strings.Split(strings.TrimSuffix(keyHelp(), "\n"), "\n")
+	if len(lines) != len(defaultBindings) {
+		t.Fatalf("%d lines for %d bindings", len(lines), len(defaultBindings))
+	}
+	keysCol, descCol := -1, -1
+	for i, bd := range defaultBindings {
+		line := lines[i]
+		head := len("  " + string(bd.action))
+		k := strings.Index(line[head:], " "+keyLabel(bd.keys[0])) + 1
+		if k > 0 {
+			k += head
+		}
+		d := strings.LastIndex(line, " "+bd.desc) + 1
+		if !strings.HasPrefix(line, "  "+string(bd.action)+" ") || k <= 0 || d <= 0 {
+			t.Fatalf("row %q doesn't read action, keys, description", line)
+		}
+		if keysCol < 0 {
+			keysCol, descCol = k, d
+		}
+		if k != keysCol || d != descCol {
+			t.Errorf("%s: keys at %d, description at %d; want %d, %d", bd.action, k, d, keysCol, descCol)
+		}
+	}
+}
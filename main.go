@@ -1,129 +1,226 @@
 package main
 
 import (
-	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"math/rand"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"regexp"
-	"runtime"
+	"slices"
 	"strings"
 	"time"
 	"unicode/utf8"
 
+	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/glamour"
 	"github.com/mattn/go-isatty"
+	"github.com/mattn/go-runewidth"
+	"github.com/muesli/termenv"
 	"github.com/spf13/cobra"
-	"golang.org/x/term"
-)
-
-// ---------- link & heading helpers ----------
-
-type link struct {
-	text         string
-	target       string // url or #anchor
-	renderedLine int
-}
-
-type heading struct {
-	text         string
-	anchor       string // github-style slug
-	renderedLine int
-}
-
-func slugify(s string) string {
-	s = strings.ToLower(s)
-	var b strings.Builder
-	for _, r := range s {
-		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == ' ' || r == '-' {
-			b.WriteRune(r)
-		}
-	}
-	return strings.Trim(strings.Join(strings.Fields(strings.ReplaceAll(b.String(), " ", "-")), "-"), "-")
-}
 
-// ANSI: SGR sequences and OSC 8 hyperlinks
-var ansiRE = regexp.MustCompile(`\x1b$begin:math:display$[0-9;]*[A-Za-z]|\\x1b$end:math:display$8;;.*?\x1b\\|\x1b\\`)
-
-func stripANSI(s string) string { return ansiRE.ReplaceAllString(s, "") }
-
-var (
-	reHeading = regexp.MustCompile(`(?m)^\s{0,3}#{1,6}\s+(.*)$`)
-	reLink    = regexp.MustCompile(`$begin:math:display$(?P<text>[^$end:math:display$]+)\]$begin:math:text$(?P<dest>[^)]+)$end:math:text$`)
+	"mdnfo/render"
 )
 
 // ---------- model ----------
 
-type monoMode int
+type monoMode = render.Mono
 
 const (
-	monoOff monoMode = iota
-	monoGreen
-	monoAmber
-	monoWhite
+	monoOff   = render.MonoOff
+	monoGreen = render.MonoGreen
+	monoAmber = render.MonoAmber
+	monoWhite = render.MonoWhite
+	monoCyan  = render.MonoCyan
+	monoBlue  = render.MonoBlue
 )
 
-func (m monoMode) String() string {
-	switch m {
-	case monoGreen:
-		return "Green"
-	case monoAmber:
-		return "Amber"
-	case monoWhite:
-		return "Paperwhite"
-	default:
-		return "Off"
-	}
-}
-
 type token struct {
 	s       string
 	isANSI  bool
 	byteLen int
 }
 
+// uiMode says where key presses go.
+type uiMode int
+
+const (
+	modeNormal  uiMode = iota // keys are actions on the document
+	modeSearch                // typing a / query
+	modeGoto                  // typing a : line or percentage
+	modeToc                   // table of contents overlay
+	modeHelp                  // key help overlay
+	modeInfo                  // document info overlay
+	modeMark                  // waiting for the digit of a bookmark to set
+	modeJump                  // waiting for the digit of a bookmark to jump to
+	modeDiscard               // waiting for y or n: leave unsaved task toggles behind?
+)
+
+// prompt is the input line's leading character, or "" when there is none.
+func (md uiMode) prompt() string {
+	switch md {
+	case modeSearch:
+		return "/"
+	case modeGoto:
+		return ":"
+	case modeMark:
+		return "set bookmark (0-9): "
+	case modeJump:
+		return "jump to bookmark (0-9): "
+	case modeDiscard:
+		return "discard changes? y/n "
+	}
+	return ""
+}
+
 type model struct {
+	files         []string   // paths given on the command line (empty for stdin)
+	fileIndex     int        // current entry in files
+	back          []location // history of jumps (Backspace)
+	forward       []location // locations undone by back
 	filename      string
 	rawMarkdown   string
+	frontMatter   frontMatterMode // --front-matter: how markdown() treats it
+	section       string          // --section: show only this heading's section of the first file
 	view          viewport.Model
-	renderedFull  string   // glamour output (with ANSI), full document
+	renderedFull  string // glamour output (with ANSI), full document
+	cache         renderCache
 	renderedLines []string // current (post-processed) lines shown
 	totalLines    int
 
+	renderTimeout  time.Duration       // --render-timeout: give up on glamour after this; 0 = never
+	renderTimedOut bool                // the last fresh render gave up, to be reported
+	lateRender     <-chan renderResult // the render that gave up, still going
+	renderWait     tea.Cmd             // waits for lateRender; Update starts it
+
 	links     []link
 	headings  []heading
-	linkIndex int // -1 none
-
-	theme     string
-	wrapWidth int
-	err       error
+	navDefs   bool           // --nav-defs: definition terms and bold lines join headings
+	footnotes map[string]int // label -> rendered line of its definition
+	linkIndex int            // -1 none
+	sourceMap []int          // rendered line -> source line, roughly; see sourceLineMap
+
+	editTasks bool   // --edit-tasks: Space toggles the selected task in the file
+	tasks     []task // task list items, when editTasks
+	taskIndex int    // -1 none
+
+	tasksDirty    bool                 // toggles shown in rawMarkdown that failed to save
+	savedMarkdown string               // the file as last loaded or saved, while tasksDirty
+	pendingLeave  func(*model) tea.Cmd // what y runs in modeDiscard
+
+	// what keys go to: the document, the input line, or an overlay
+	mode  uiMode
+	input string // text being typed in modeSearch / modeGoto
+
+	bookmarks map[int]int // digit -> YOffset, for the current document
+	statePath string      // where bookmarks are saved; "" = nowhere
+
+	// search (/, n, N)
+	searchQuery   string // committed query; "" = no highlights
+	searchMatches []searchMatch
+	searchIndex   int // active match
+
+	// table of contents overlay (t)
+	tocIndex  int // selected heading
+	tocOffset int // first visible entry
+
+	// key help overlay (?)
+	helpOffset int // first visible line
+
+	theme          string
+	styles         []string // styles T cycles through, the starting one first
+	styleIndex     int      // m.theme's place in styles
+	codeTheme      string   // chroma style for fenced code ("" = theme default)
+	quoteBar       rune     // --quote-char: blockquote bar glyph; 0 = the theme's
+	quoteColors    []string // --quote-colors: blockquote bar colors by depth
+	preserveBreaks bool     // --preserve-breaks: paragraphs keep their line breaks
+	wrapWidth      int
+	maxWidth       int      // --max-width: cap on the auto wrap width; 0 = none
+	clampWrap      bool     // --clamp-wrap: keep --wrap within the screen
+	wrapWarned     bool     // said that --wrap is wider than the screen
+	wrapMode       wrapMode // --wrap-mode, cycled with w
+	cols           int      // --cols: canvas width regardless of the terminal
+	raw            bool     // --raw: show the text verbatim, no Markdown
+	tabWidth       int      // tab stops every tabWidth columns; 0 keeps tabs
+	dimRead        bool     // dim lines above readMark
+	readMark       int      // furthest YOffset reached in this document
+	lineNumbers    bool
+	highlightLinks bool // underline every link, L
+	gutter         int  // columns taken by line numbers (0 when off)
+	xOffset        int  // first visible column when panning wide lines
+	err            error
+	inline         bool // --no-altscreen: drawn in the normal screen
+	quitting       bool // the last frame is being drawn
 
 	// file metadata (for header)
 	fileMod  time.Time
 	fileSize int64
+	words    int    // prose word count, for the info panel
+	watch    bool   // reload when fileMod changes on disk
+	follow   bool   // --follow: stream in appended text, tail -f style
+	encoding string // --encoding, for reloads and file switches
+
+	// --diff: the document compared against, and where its changes start
+	diffBase  string // the old document's Markdown
+	diffName  string // its name; "" = not comparing
+	diffHunks []int
+	diffHunk  int // the change last jumped to; -1 = none
 
 	// smooth scroll animation (works for single-line and page)
 	animating    bool
 	targetOffset int
+	ticking      bool // a scrollTick is pending
+	fps          int  // ticker rate, --fps
+	easing       scrollEasing
+	progress     progressLabel // the progress bar's label, --progress
+	barChars     barChars      // the progress bar's glyphs, --bar-style / --bar-chars
+	timeFormat   timeFormat    // the header's file time, --time
+	linearStep   int           // lines per frame for scrollLinear, fixed per scroll
+
+	// auto-scroll (a): autoscrollSpeed lines a second, the fraction of a
+	// line owed carried in autoscrollAcc
+	autoscroll      bool
+	autoscrollSpeed float64
+	autoscrollAcc   float64
 
 	// CRT/Easy-win toggles
 	scanlines bool
+	inverse   bool // reverse video (--inverse, v)
 	mono      monoMode
 	fixed8025 bool
 	bbsChrome bool
-	degauss   int // remaining frames; when >0, active
-	rxBlink   int // frames remaining
-	txBlink   int // frames remaining
-	rand      *rand.Rand
+	minimal   bool // no header or footer, z
+	cursor    bool // blinking block cursor
+	phosphor  bool // afterglow while scrolling (always on in mono)
+	bloom     bool // mono glow by glyph density (truecolor only)
+	degauss   int  // remaining frames; when >0, active
+
+	degaussStrength int // 1-3: jitter, duration and color wobble
+	rxBlink         int // frames remaining
+	txBlink         int // frames remaining
+	rand            *rand.Rand
+
+	keys keymap
+
+	// phosphor afterglow: previous frame's visible lines (plain)
+	ghost       []string
+	ghostFrames int // frames remaining
+
+	wrapBadgeFrames int // frames left to show the wrap width badge
+
+	// transient status toast shown over the footer (see flash)
+	statusMsg    string
+	statusFrames int // frames remaining
 
 	// Capability guess
 	truecolor  bool
 	palette256 bool
+	graphics   graphicsProto
+	osc8       bool   // emit clickable OSC 8 hyperlinks
+	browser    string // command template for external links ("" = OS default)
+	noColor    bool   // NO_COLOR / --no-color: never emit SGR
 
 	// Modem/baud streaming
 	baudrate         int       // e.g., 115200 (bits/sec)
@@ -132,278 +229,173 @@ type model struct {
 	txBytesAvailable int       // how many bytes should be visible by now
 	txLastAvail      int       // prev avail, to blink RX
 	streamDone       bool      // once all bytes visible
+	paused           bool      // stream frozen (space)
+	pausedAt         time.Time // when the pause began
 	streamTokens     []token   // full stream tokenized (ANSI tokens + plain)
 	streamTotalBytes int       // total bytes across tokens
+	handshaking      bool      // modem chatter before the stream (--handshake)
+	handshakeStart   time.Time
+	loop             bool      // --loop: stream again after loopPause
+	loopAt           time.Time // when the finished stream restarts
 }
 
-// ---------- rendering ----------
+// ---------- status toasts ----------
 
-func renderMarkdown(raw string, width int, style string) (string, error) {
-	opts := []glamour.TermRendererOption{
-		glamour.WithWordWrap(width),
-	}
-
-	switch strings.ToLower(strings.TrimSpace(style)) {
-	case "", "auto":
-		opts = append(opts, glamour.WithAutoStyle())
-	case "dark", "light", "notty", "dracula", "pink":
-		opts = append(opts, glamour.WithStylePath(style))
-	default:
-		// If it's a file path to a JSON style, use it; else fall back to auto.
-		if _, err := os.Stat(style); err == nil {
-			opts = append(opts, glamour.WithStylesFromJSONFile(style))
-		} else {
-			opts = append(opts, glamour.WithAutoStyle())
-		}
-	}
+const statusDuration = 1500 * time.Millisecond
 
-	r, err := glamour.NewTermRenderer(opts...)
-	if err != nil {
-		return "", err
-	}
-	return r.Render(raw)
+// flash shows msg over the footer for statusDuration. The caller
+// must make sure the ticker is running (return m.tick()).
+func (m *model) flash(msg string) {
+	m.statusMsg = msg
+	m.statusFrames = m.frames(statusDuration)
 }
 
-func (m *model) recalcRendered(width, height int) {
-	// Fixed 80x25 mode keeps a classic canvas
-	if m.fixed8025 {
-		width = 80
-		height = 25
-	}
-	bodyHeight := height - 2 // header + footer/status
-	if bodyHeight < 1 {
-		bodyHeight = 1
-	}
-	wrap := m.wrapWidth
-	if wrap <= 0 {
-		if m.fixed8025 {
-			wrap = 80
-		} else {
-			wrap = width
-		}
-	}
-	out, err := renderMarkdown(m.rawMarkdown, wrap, m.theme)
-	if err != nil {
-		m.err = err
-		return
-	}
-	m.renderedFull = out
+// ---------- animation helpers ----------
 
-	// Prepare the transmission tokens for modem emulation
-	m.prepareStreamTokens()
+type scrollTick struct{}
 
-	// Build view from current tx progress
-	part := m.partialStreamString()
-	post := m.applyPostEffects(part)
-	m.renderedLines = strings.Split(strings.TrimRight(post, "\n"), "\n")
-	m.totalLines = len(m.renderedLines)
+// scrollEasing is the --scroll curve.
+type scrollEasing int
 
-	if m.view.Width != width || m.view.Height != bodyHeight {
-		m.view.Width = width
-		m.view.Height = bodyHeight
-	}
-	m.view.SetContent(strings.Join(m.renderedLines, "\n"))
-	m.buildIndexes()
-}
+const (
+	scrollEase    scrollEasing = iota // a fifth of the remaining distance per frame
+	scrollLinear                      // constant speed, arriving in linearFrames
+	scrollInstant                     // no animation
+)
 
-func (m *model) applyPostEffects(s string) string {
-	// Optional monochrome filter: strip all color, then recolor lines uniformly
-	if m.mono != monoOff {
-		plain := stripANSI(s)
-		colorOpen, colorClose := monoSGR(m.mono, m.truecolor, m.palette256)
-		s = colorOpen + plain + colorClose
-	}
-
-	// Scanlines (and degauss jitter)
-	if m.scanlines || m.degauss > 0 {
-		lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
-		for i := range lines {
-			if m.degauss > 0 {
-				off := 0
-				if m.rand.Intn(3) == 0 {
-					off = m.rand.Intn(2)
-				}
-				if off > 0 {
-					lines[i] = strings.Repeat(" ", off) + lines[i]
-				}
-			}
-			if i%2 == 1 {
-				lines[i] = "\x1b[2m" + lines[i] + "\x1b[22m"
-			}
-		}
-		s = strings.Join(lines, "\n")
-	}
+const linearFrames = 12
 
-	// Brief flash at the start of degauss
-	if m.degauss > 0 && m.degauss > degaussTotalFrames()-degaussFlashFrames() {
-		s = "\x1b[7m" + s + "\x1b[27m"
-	}
+// defaultFPS is the --fps default: smooth without cooking the CPU.
+const defaultFPS = 60
 
-	// Clamp to 80 columns visually in 80x25
-	if m.fixed8025 {
-		s = hardClipColumns(s, 80)
-	}
-	return s
+func scrollTicker(fps int) tea.Cmd {
+	return tea.Tick(time.Second/time.Duration(fps), func(time.Time) tea.Msg { return scrollTick{} })
 }
 
-func hardClipColumns(s string, cols int) string {
-	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
-	for i := range lines {
-		lines[i] = truncateVisibleToWidth(lines[i], cols)
-	}
-	return strings.Join(lines, "\n")
+// frames converts d to a number of ticks at the current frame rate.
+func (m *model) frames(d time.Duration) int {
+	return max(1, int(d*time.Duration(m.fps)/time.Second))
 }
 
-// ---------- streaming / baud emulation ----------
-
-func (m *model) prepareStreamTokens() {
-	// Tokenize renderedFull into ANSI and plain segments
-	s := m.renderedFull
-	m.streamTokens = m.streamTokens[:0]
-	m.streamTotalBytes = 0
-
-	idxs := ansiRE.FindAllStringIndex(s, -1)
-	last := 0
-	for _, span := range idxs {
-		// plain before ANSI
-		if span[0] > last {
-			chunk := s[last:span[0]]
-			if chunk != "" {
-				bt := len([]byte(chunk))
-				m.streamTokens = append(m.streamTokens, token{s: chunk, isANSI: false, byteLen: bt})
-				m.streamTotalBytes += bt
-			}
-		}
-		// the ANSI token
-		seq := s[span[0]:span[1]]
-		bt := len([]byte(seq))
-		m.streamTokens = append(m.streamTokens, token{s: seq, isANSI: true, byteLen: bt})
-		m.streamTotalBytes += bt
-		last = span[1]
-	}
-	// tail plain
-	if last < len(s) {
-		chunk := s[last:]
-		bt := len([]byte(chunk))
-		m.streamTokens = append(m.streamTokens, token{s: chunk, isANSI: false, byteLen: bt})
-		m.streamTotalBytes += bt
-	}
-
-	// (Re)start stream timing if not already started or if we re-rendered
-	if m.txStart.IsZero() {
-		m.txStart = time.Now()
-	}
-	// bytesPerSecond from baudrate with 8N1 overhead ~10 bits/byte
-	if m.baudrate > 0 {
-		m.bytesPerSecond = float64(m.baudrate) / 10.0
-	} else {
-		m.bytesPerSecond = 0
-	}
-	// If baudrate <= 0, show all immediately
-	if m.bytesPerSecond <= 0 {
-		m.txBytesAvailable = m.streamTotalBytes
-		m.streamDone = true
-	} else if m.txBytesAvailable > m.streamTotalBytes {
-		m.txBytesAvailable = m.streamTotalBytes
-		m.streamDone = true
+// busy reports whether anything on screen changes from frame to frame.
+func (m *model) busy() bool {
+	streaming := !m.streamDone && m.bytesPerSecond > 0 && !m.paused
+	return m.handshaking || streaming || m.loop || m.animating || m.autoscroll || m.cursor || m.degauss > 0 ||
+		m.ghostFrames > 0 || m.rxBlink > 0 || m.txBlink > 0 || m.statusFrames > 0 || m.wrapBadgeFrames > 0
+}
+
+// tick starts the ticker unless a tick is already pending, so key presses
+// that kick off animation don't stack up extra ticker loops.
+func (m *model) tick() tea.Cmd {
+	if m.ticking {
+		return nil
 	}
+	m.ticking = true
+	return scrollTicker(m.fps)
 }
 
-func (m *model) partialStreamString() string {
-	if m.bytesPerSecond <= 0 {
-		return m.renderedFull
+func (m *model) startScrollTo(target int) tea.Cmd {
+	maxOffset := max(0, m.totalLines-m.view.Height)
+	if target < 0 {
+		target = 0
+	}
+	if target > maxOffset {
+		target = maxOffset
 	}
-	// Calculate allowed bytes based on elapsed time
-	elapsed := time.Since(m.txStart).Seconds()
-	allowed := int(elapsed * m.bytesPerSecond)
-	if allowed > m.streamTotalBytes {
-		allowed = m.streamTotalBytes
+	m.targetOffset = target
+	if m.view.YOffset == m.targetOffset || m.easing == scrollInstant {
+		m.animating = false
+		m.view.SetYOffset(target)
+		return nil
 	}
-	if allowed < 0 {
-		allowed = 0
+	if m.easing == scrollLinear {
+		dist := target - m.view.YOffset
+		if dist < 0 {
+			dist = -dist
+		}
+		m.linearStep = max(1, (dist+linearFrames-1)/linearFrames)
 	}
+	m.animating = true
+	return m.tick()
+}
 
-	// Blink RX if new bytes arrived
-	if allowed > m.txLastAvail {
-		m.rxBlink = 6
-	}
-	m.txLastAvail = allowed
-	m.txBytesAvailable = allowed
-	m.streamDone = allowed >= m.streamTotalBytes
+// ---------- file watching ----------
 
-	if allowed == 0 {
-		return ""
-	}
+type watchTick struct{}
 
-	var b strings.Builder
-	remain := allowed
-	for _, tk := range m.streamTokens {
-		if remain <= 0 {
-			break
-		}
-		if tk.byteLen <= remain {
-			b.WriteString(tk.s)
-			remain -= tk.byteLen
-			continue
-		}
-		// Need to cut inside this token
-		if tk.isANSI {
-			// Never include partial ANSI; skip it (acts like still buffering).
-			break
-		}
-		// Cut plain text at rune boundaries within byte budget
-		wrote := writeRunesWithinBytes(&b, tk.s, remain)
-		remain -= wrote
-		break
-	}
-	return b.String()
+type fileChangedMsg struct {
+	path string
+	mod  time.Time
 }
 
-func writeRunesWithinBytes(b *strings.Builder, s string, budget int) int {
-	// Append as many runes as fit within 'budget' bytes (UTF-8)
-	written := 0
-	for _, r := range s {
-		n := utf8.RuneLen(r)
-		if n < 0 {
-			n = 1
+// watchFile polls path and reports a fileChangedMsg once its mod time
+// differs from since; otherwise it reports a watchTick to be re-armed.
+func watchFile(path string, since time.Time) tea.Cmd {
+	return tea.Tick(500*time.Millisecond, func(time.Time) tea.Msg {
+		fi, err := os.Stat(path)
+		if err != nil || fi.ModTime().Equal(since) {
+			return watchTick{}
 		}
-		if written+n > budget {
-			break
-		}
-		b.WriteRune(r)
-		written += n
-	}
-	return written
+		return fileChangedMsg{path: path, mod: fi.ModTime()}
+	})
 }
 
-// ---------- animation helpers ----------
+// ---------- phosphor persistence ----------
 
-type scrollTick struct{}
+const ghostTotalFrames = 2
 
-func scrollTicker() tea.Cmd {
-	// ~60 FPS; smooth without cooking the CPU
-	return tea.Tick(time.Second/60, func(time.Time) tea.Msg { return scrollTick{} })
-}
+func (m *model) phosphorActive() bool { return !m.noColor && (m.phosphor || m.mono != monoOff) }
 
-func (m *model) startScrollTo(target int) tea.Cmd {
-	maxOffset := max(0, m.totalLines-m.view.Height)
-	if target < 0 {
-		target = 0
+// captureGhost remembers the outgoing visible lines before a scroll step.
+func (m *model) captureGhost() {
+	lo := clamp(m.view.YOffset, 0, len(m.renderedLines))
+	hi := min(lo+m.view.Height, len(m.renderedLines))
+	m.ghost = m.ghost[:0]
+	for _, l := range m.renderedLines[lo:hi] {
+		m.ghost = append(m.ghost, stripANSI(l))
 	}
-	if target > maxOffset {
-		target = maxOffset
+	m.ghostFrames = ghostTotalFrames
+}
+
+// blendGhost draws the ghost's glyphs, dimmed, into blank cells of body.
+func blendGhost(body string, ghost []string) string {
+	lines := strings.Split(body, "\n")
+	for i := range lines {
+		if i >= len(ghost) || strings.TrimSpace(ghost[i]) == "" {
+			continue
+		}
+		lines[i] = blendLine(lines[i], []rune(ghost[i]))
 	}
-	m.targetOffset = target
-	if m.view.YOffset == m.targetOffset {
-		m.animating = false
-		return nil
+	return strings.Join(lines, "\n")
+}
+
+func blendLine(line string, ghost []rune) string {
+	seqs := ansiRE.FindAllStringIndex(line, -1)
+	var b strings.Builder
+	col := 0
+	for i := 0; i < len(line); {
+		if len(seqs) > 0 && i == seqs[0][0] {
+			b.WriteString(line[seqs[0][0]:seqs[0][1]])
+			i = seqs[0][1]
+			seqs = seqs[1:]
+			continue
+		}
+		r, n := utf8.DecodeRuneInString(line[i:])
+		i += n
+		if r == ' ' && col < len(ghost) && ghost[col] != ' ' && runewidth.RuneWidth(ghost[col]) == 1 {
+			b.WriteString("\x1b[2m" + string(ghost[col]) + "\x1b[22m")
+		} else {
+			b.WriteRune(r)
+		}
+		col++
 	}
-	m.animating = true
-	return scrollTicker()
+	return b.String()
 }
 
-func degaussTotalFrames() int { return 30 }
-func degaussFlashFrames() int { return 6 }
+// Degauss lasts longer and flashes longer with --degauss-strength: 30 and
+// 6 frames at the default strength of 1, 60 and 12 at 3.
+func (m *model) degaussTotalFrames() int { return 15 + 15*m.degaussStrength }
+func (m *model) degaussFlashFrames() int { return 3 + 3*m.degaussStrength }
 
 // ---------- bubbletea plumbing ----------
 
@@ -412,35 +404,122 @@ func initialModel(filename, raw, theme string, wrap int, mod time.Time, size int
 	v.YPosition = 1
 
 	seed := time.Now().UnixNano()
-	truecolor, palette256 := detectColorCaps()
+	// the TUI may ask the terminal; one-shot output must not wait on it
+	probe := !flags.print && flags.pager == ""
+	truecolor, palette256 := detectColorCaps(flags.color, probe)
 
 	m := model{
-		filename:    filename,
-		rawMarkdown: raw,
-		view:        v,
-		linkIndex:   -1,
-		theme:       theme,
-		wrapWidth:   wrap,
-		fileMod:     mod,
-		fileSize:    size,
-		scanlines:   flags.scanlines,
-		mono:        flags.mono,
-		fixed8025:   flags.fixed8025,
-		bbsChrome:   flags.bbs,
-		rand:        rand.New(rand.NewSource(seed)),
-		truecolor:   truecolor,
-		palette256:  palette256,
-		baudrate:    flags.baudrate,
+		filename:        filename,
+		rawMarkdown:     raw,
+		view:            v,
+		linkIndex:       -1,
+		taskIndex:       -1,
+		editTasks:       flags.editTasks && filename != stdinName && !isURL(filename),
+		theme:           theme,
+		codeTheme:       flags.codeTheme,
+		preserveBreaks:  flags.preserveBreaks,
+		renderTimeout:   flags.renderTimeout,
+		quoteBar:        flags.quoteBar,
+		quoteColors:     flags.quoteColors,
+		diffHunk:        -1,
+		inline:          flags.noAltScreen,
+		frontMatter:     flags.frontMatter,
+		navDefs:         flags.navDefs,
+		section:         flags.section,
+		wrapWidth:       wrap,
+		cols:            flags.cols,
+		raw:             flags.raw,
+		tabWidth:        flags.tabWidth,
+		dimRead:         flags.dimRead,
+		lineNumbers:     flags.lineNumbers,
+		highlightLinks:  flags.highlightLinks,
+		minimal:         flags.minimal,
+		fileMod:         mod,
+		fileSize:        size,
+		words:           countWords(sectionOf(markdownBody(raw, flags.frontMatter), flags.section)),
+		scanlines:       flags.scanlines,
+		inverse:         flags.inverse,
+		mono:            flags.mono,
+		fixed8025:       flags.fixed8025,
+		bbsChrome:       flags.bbs,
+		cursor:          flags.cursor,
+		phosphor:        flags.phosphor,
+		bloom:           flags.bloom,
+		degaussStrength: flags.degaussStrength,
+		rand:            rand.New(rand.NewSource(seed)),
+		truecolor:       truecolor,
+		palette256:      palette256,
+		graphics:        detectGraphics(probe),
+		osc8:            flags.osc8,
+		browser:         browserCommand(flags.browser),
+		baudrate:        flags.baudrate,
+		handshaking:     flags.handshake && flags.baudrate > 0,
+		loop:            flags.loop && flags.baudrate > 0,
+		watch:           (flags.watch || flags.follow) && filename != stdinName && !isURL(filename),
+		follow:          flags.follow && filename != stdinName && !isURL(filename),
+		encoding:        flags.encoding,
+		easing:          flags.scroll,
+		progress:        flags.progress,
+		barChars:        flags.barChars,
+		timeFormat:      flags.timeFormat,
+		ticking:         true, // Init starts the ticker
+		fps:             flags.fps,
+		autoscrollSpeed: flags.autoscrollSpeed,
+		wrapMode:        flags.wrapMode,
+		maxWidth:        flags.maxWidth,
+		clampWrap:       flags.clampWrap,
+		keys:            flags.keys,
+	}
+	if m.keys == nil {
+		m.keys = defaultKeymap()
+	}
+	// NO_COLOR (https://no-color.org) or --no-color: plain text only
+	if flags.noColor || (os.Getenv("NO_COLOR") != "" && !flags.forceColor) {
+		m.noColor = true
+		m.theme = "notty"
+		m.codeTheme = ""
+		m.mono = monoOff
+		m.scanlines = false
+		m.inverse = false
+		m.graphics = graphicsNone
 	}
 	return m
 }
 
 func (m model) Init() tea.Cmd {
 	// Drive ticker for animations and streaming
-	return scrollTicker()
+	cmds := []tea.Cmd{scrollTicker(m.fps), m.clockTick()}
+	if m.watch {
+		cmds = append(cmds, watchFile(m.filename, m.fileMod))
+	}
+	return tea.Batch(cmds...)
 }
 
+// Update handles msg, then restarts the ticker if the handler left
+// something moving (a blink, a toast, a scroll) while it was stopped.
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	next, cmd := m.update(msg)
+	nm, ok := next.(model)
+	if !ok {
+		return next, cmd
+	}
+	if nm.renderWait != nil {
+		cmd = tea.Batch(cmd, nm.renderWait)
+		nm.renderWait = nil
+	}
+	if nm.dimRead && nm.view.YOffset > nm.readMark {
+		// the read shadow follows the reader down, never back up
+		nm.readMark = nm.view.YOffset
+		nm.refreshContent()
+	}
+	if nm.ticking || !nm.busy() {
+		return nm, cmd
+	}
+	tick := nm.tick()
+	return nm, tea.Batch(cmd, tick)
+}
+
+func (m model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.recalcRendered(msg.Width, msg.Height)
@@ -449,108 +528,132 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, cmd
 
 	case tea.KeyMsg:
-		// quit on q or Q
-		if msg.String() == "q" || msg.String() == "Q" {
-			return m, tea.Quit
+		// Ctrl+C always gets out, whatever is open
+		if msg.Type == tea.KeyCtrlC {
+			return m, m.quit()
 		}
-		switch msg.Type {
-		case tea.KeyEsc:
-			return m, tea.Quit
-
-		// Smooth single-line scrolling via animator
-		case tea.KeyUp:
-			m.txBlink = 6
-			return m, m.startScrollTo(m.view.YOffset - 1)
-		case tea.KeyDown:
-			m.txBlink = 6
-			return m, m.startScrollTo(m.view.YOffset + 1)
-
-		// Smooth page scrolling via animator
-		case tea.KeyPgUp, tea.KeyCtrlB:
-			m.txBlink = 6
-			return m, m.startScrollTo(m.view.YOffset - m.view.Height)
-		case tea.KeyPgDown, tea.KeyCtrlF:
-			m.txBlink = 6
-			return m, m.startScrollTo(m.view.YOffset + m.view.Height)
-
-		case tea.KeyHome:
-			m.txBlink = 6
-			m.view.GotoTop()
+		// any key ends --loop: someone is reading now
+		m.loop = false
+		m.loopAt = time.Time{}
+		// any key skips the handshake
+		if m.handshaking {
+			m.endHandshake()
 			return m, nil
-		case tea.KeyEnd:
-			m.txBlink = 6
-			m.view.GotoBottom()
-			return m, nil
-
-		case tea.KeyTab:
-			if len(m.links) > 0 {
-				m.txBlink = 6
-				if m.linkIndex == -1 {
-					m.linkIndex = 0
-				} else {
-					m.linkIndex = (m.linkIndex + 1) % len(m.links)
-				}
-				m.scrollToLink()
+		}
+		// the error screen only reloads, redraws or quits
+		if m.err != nil && m.mode != modeDiscard {
+			switch a := m.keys.lookup(msg.String()); a {
+			case actReload, actRedraw, actQuit:
+				cmd, _ := m.handleAction(a)
+				return m, cmd
 			}
 			return m, nil
-		case tea.KeyShiftTab:
-			if len(m.links) > 0 {
-				m.txBlink = 6
-				if m.linkIndex == -1 {
-					m.linkIndex = len(m.links) - 1
-				} else {
-					m.linkIndex = (m.linkIndex - 1 + len(m.links)) % len(m.links)
-				}
-				m.scrollToLink()
-			}
+		}
+		// the input line and overlays swallow keys until closed; Esc
+		// closes them, and only quits from modeNormal
+		switch m.mode {
+		case modeSearch, modeGoto:
+			cmd := m.updateInput(msg)
+			return m, cmd
+		case modeToc:
+			m.updateToc(msg)
 			return m, nil
-		case tea.KeyEnter:
-			m.txBlink = 6
-			if m.linkIndex >= 0 && m.linkIndex < len(m.links) {
-				m.followLink(m.links[m.linkIndex])
-			}
+		case modeHelp:
+			m.updateHelp(msg)
+			return m, nil
+		case modeInfo:
+			m.updateInfo(msg)
+			return m, nil
+		case modeMark, modeJump:
+			cmd := m.updateBookmark(msg)
+			return m, cmd
+		case modeDiscard:
+			cmd := m.updateDiscard(msg)
+			return m, cmd
+		}
+		if cmd, ok := m.handleAction(m.keys.lookup(msg.String())); ok {
+			return m, cmd
+		}
+
+	case tea.MouseMsg:
+		if m.mode != modeNormal {
 			return m, nil
+		}
+		cmd := m.handleMouse(tea.MouseEvent(msg))
+		return m, cmd
 
-		default:
-			switch strings.ToLower(msg.String()) {
-			case "s":
-				m.scanlines = !m.scanlines
-				m.rxBlink = 6
-				m.recalcRendered(m.view.Width, m.view.Height+2)
-				return m, nil
-			case "m":
-				m.mono++
-				if m.mono > monoWhite {
-					m.mono = monoOff
-				}
-				m.rxBlink = 6
-				m.recalcRendered(m.view.Width, m.view.Height+2)
-				return m, nil
-			case "b":
-				m.bbsChrome = !m.bbsChrome
-				m.rxBlink = 6
-				m.recalcRendered(m.view.Width, m.view.Height+2)
-				return m, nil
-			case "d":
-				m.degauss = degaussTotalFrames()
-				m.rxBlink, m.txBlink = 12, 12
-				return m, scrollTicker()
+	case watchTick:
+		return m, watchFile(m.filename, m.fileMod)
+
+	case renderDoneMsg:
+		m.finishRender(msg)
+		return m, nil
+
+	case clockMsg:
+		// the header's relative file time has moved on; returning redraws it
+		return m, m.clockTick()
+
+	case openFailedMsg:
+		m.flash("open failed: " + msg.err.Error())
+		return m, m.tick()
+
+	case editorDoneMsg:
+		if msg.err != nil {
+			m.flash("editor: " + msg.err.Error())
+			return m, m.tick()
+		}
+		return m, m.confirmLeave((*model).reload)
+
+	case fileChangedMsg:
+		if msg.path == m.filename && !msg.mod.Equal(m.fileMod) {
+			if m.tasksDirty {
+				// unasked, the toggles on screen win; r reloads
+				m.fileMod = msg.mod
+				m.flash("file changed on disk; unsaved task toggles kept (r reloads)")
+			} else if m.follow {
+				m.appendFile()
+			} else {
+				m.reloadFile()
 			}
+			tick := m.tick()
+			return m, tea.Batch(tick, watchFile(m.filename, m.fileMod))
 		}
+		return m, watchFile(m.filename, m.fileMod)
 
 	case scrollTick:
 		// Drive animation, blink, degauss, smooth scroll, and streaming progress
+		m.ticking = false
 		needsRecalc := false
 
+		if m.handshaking {
+			if time.Since(m.handshakeStart) >= handshakeDuration {
+				m.endHandshake()
+			} else {
+				m.refreshContent()
+			}
+			needsRecalc = true
+		}
+
 		// Streaming: recompute partial view based on time
-		if !m.streamDone && m.bytesPerSecond > 0 {
-			_ = m.txBytesAvailable
+		if !m.handshaking && !m.streamDone && m.bytesPerSecond > 0 && !m.paused {
+			// --follow keeps the view pinned to the bottom unless the
+			// reader has scrolled away from it
+			stick := m.follow && !m.animating && m.view.AtBottom()
 			// Update allowed bytes and rebuild current content
-			part := m.partialStreamString()
-			post := m.applyPostEffects(part)
-			m.renderedLines = strings.Split(strings.TrimRight(post, "\n"), "\n")
-			m.totalLines = len(m.renderedLines)
-			m.view.SetContent(strings.Join(m.renderedLines, "\n"))
+			m.refreshContent()
+			if stick {
+				m.view.GotoBottom()
+			}
+			needsRecalc = true
+		}
+
+		// --loop: a pause once everything has arrived, then start over
+		if m.loop && !m.handshaking && m.streamDone && m.bytesPerSecond > 0 {
+			if m.loopAt.IsZero() {
+				m.loopAt = time.Now().Add(loopPause)
+			} else if !time.Now().Before(m.loopAt) {
+				m.restartStream()
+			}
 			needsRecalc = true
 		}
 
@@ -561,6 +664,12 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if cur != tgt {
 				diff := tgt - cur
 				step := diff / 5
+				if m.easing == scrollLinear {
+					step = m.linearStep
+					if diff < 0 {
+						step = -step
+					}
+				}
 				if step == 0 {
 					if diff > 0 {
 						step = 1
@@ -572,6 +681,9 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if (diff > 0 && newOff > tgt) || (diff < 0 && newOff < tgt) {
 					newOff = tgt
 				}
+				if m.phosphorActive() {
+					m.captureGhost()
+				}
 				m.view.SetYOffset(newOff)
 				if newOff == tgt {
 					m.animating = false
@@ -582,13 +694,33 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 
+		// Auto-scroll: whole lines as the fractional position crosses them
+		if m.autoscroll && !m.animating {
+			m.autoscrollAcc += m.autoscrollSpeed / float64(m.fps)
+			if n := int(m.autoscrollAcc); n > 0 {
+				m.autoscrollAcc -= float64(n)
+				if m.phosphorActive() {
+					m.captureGhost()
+				}
+				m.view.SetYOffset(m.view.YOffset + n)
+				needsRecalc = true
+			}
+			// while text is still arriving, the end keeps moving
+			if m.view.AtBottom() && m.streamDone {
+				m.autoscroll = false
+				m.flash("auto-scroll: end")
+				needsRecalc = true
+			}
+		}
+
 		if m.degauss > 0 {
 			m.degauss--
 			// Re-apply post effects for jitter/flash while active
-			part := m.partialStreamString()
-			post := m.applyPostEffects(part)
-			m.renderedLines = strings.Split(strings.TrimRight(post, "\n"), "\n")
-			m.view.SetContent(strings.Join(m.renderedLines, "\n"))
+			m.refreshContent()
+			needsRecalc = true
+		}
+		if m.ghostFrames > 0 {
+			m.ghostFrames--
 			needsRecalc = true
 		}
 		if m.rxBlink > 0 {
@@ -599,8 +731,19 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.txBlink--
 			needsRecalc = true
 		}
-		if needsRecalc || m.scanlines || m.bbsChrome || m.degauss > 0 || m.animating {
-			return m, scrollTicker()
+		if m.statusFrames > 0 {
+			m.statusFrames--
+			needsRecalc = true
+		}
+		if m.wrapBadgeFrames > 0 {
+			m.wrapBadgeFrames--
+			needsRecalc = true
+		}
+		// Static effects (scanlines, BBS chrome) are drawn with the content
+		// and need no frames of their own; stop when nothing is moving.
+		if needsRecalc || m.busy() {
+			tick := m.tick()
+			return m, tick
 		}
 	}
 
@@ -609,269 +752,757 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
-func (m *model) scrollToLink() {
-	if m.linkIndex < 0 || m.linkIndex >= len(m.links) {
-		return
-	}
-	line := m.links[m.linkIndex].renderedLine
-	if line < 0 {
+// location is a document and scroll position to come back to.
+type location struct {
+	doc    document
+	offset int
+}
+
+// loadFile switches to files[i], restarting the stream from the top.
+func (m *model) loadFile(i int) {
+	doc, err := readDocument(m.files[i], m.encoding)
+	if err != nil {
+		m.flash(err.Error())
 		return
 	}
-	target := line - m.view.Height/2
-	if target < 0 {
-		target = 0
-	}
-	if target > m.totalLines-m.view.Height {
-		target = m.totalLines - m.view.Height
+	m.fileIndex = i
+	m.showDocument(doc)
+}
+
+// here is the current document and scroll position. Task toggles that
+// failed to save aren't part of it: coming back shows the file as it is
+// on disk, which is what a later toggle has to match to save.
+func (m *model) here() location {
+	doc := document{name: m.filename, raw: m.onDisk(), mod: m.fileMod, size: m.fileSize}
+	return location{doc: doc, offset: m.view.YOffset}
+}
+
+// onDisk is the document text as last loaded or saved.
+func (m *model) onDisk() string {
+	if m.tasksDirty {
+		return m.savedMarkdown
 	}
-	if target < 0 {
-		target = 0
+	return m.rawMarkdown
+}
+
+// otherDocument reports whether restoring loc replaces the text on screen
+// rather than only scrolling it.
+func (m *model) otherDocument(loc location) bool {
+	return loc.doc.name != m.filename || loc.doc.raw != m.onDisk()
+}
+
+const historyLimit = 100
+
+// record saves the current location before a jump and drops any forward
+// history, like a browser.
+func (m *model) record() {
+	m.back = append(m.back, m.here())
+	if len(m.back) > historyLimit {
+		m.back = m.back[len(m.back)-historyLimit:]
 	}
-	m.view.SetYOffset(target)
+	m.forward = nil
 }
 
-func (m *model) followLink(l link) {
-	dest := strings.TrimSpace(l.target)
-	if dest == "" {
+// goBack returns to the location saved before the last jump.
+func (m *model) goBack() {
+	if len(m.back) == 0 {
+		m.flash("no previous location")
 		return
 	}
-	if strings.HasPrefix(dest, "#") {
-		anc := strings.TrimPrefix(dest, "#")
-		for _, h := range m.headings {
-			if h.anchor == anc || slugify(h.text) == anc || slugify(anc) == h.anchor {
-				if h.renderedLine >= 0 {
-					m.view.SetYOffset(clamp(h.renderedLine, 0, max(0, m.totalLines-m.view.Height)))
-					return
-				}
-			}
-		}
-		if l.renderedLine >= 0 {
-			m.view.SetYOffset(clamp(l.renderedLine, 0, max(0, m.totalLines-m.view.Height)))
-		}
+	loc := m.back[len(m.back)-1]
+	m.back = m.back[:len(m.back)-1]
+	m.forward = append(m.forward, m.here())
+	m.restore(loc)
+}
+
+// goForward re-does the jump undone by the last goBack.
+func (m *model) goForward() {
+	if len(m.forward) == 0 {
+		m.flash("no next location")
 		return
 	}
-	_ = openURL(dest)
+	loc := m.forward[len(m.forward)-1]
+	m.forward = m.forward[:len(m.forward)-1]
+	m.back = append(m.back, m.here())
+	m.restore(loc)
 }
 
-func clamp(v, lo, hi int) int {
-	if v < lo {
-		return lo
-	}
-	if v > hi {
-		return hi
+// restore shows loc; another document comes back in full rather than
+// streamed again.
+func (m *model) restore(loc location) {
+	if m.otherDocument(loc) {
+		m.showDocument(loc.doc)
+		m.skipStream()
 	}
-	return v
+	m.animating = false
+	m.view.SetYOffset(clamp(loc.offset, 0, max(0, m.totalLines-m.view.Height)))
 }
-func max(a, b int) int {
-	if a > b {
-		return a
+
+// localDocument resolves a link target to an existing Markdown file next to
+// the current one, splitting off any #fragment.
+func (m *model) localDocument(dest string) (path, frag string, ok bool) {
+	if strings.Contains(dest, "://") || strings.HasPrefix(dest, "mailto:") {
+		return "", "", false
 	}
-	return b
+	path, frag, _ = strings.Cut(dest, "#")
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown", ".mdown", ".mkd":
+	default:
+		return "", "", false
+	}
+	if isURL(m.filename) {
+		// relative to a fetched document: fetch the sibling too
+		u, ok := resolveURL(m.filename, path)
+		return u, frag, ok
+	}
+	if !filepath.IsAbs(path) {
+		dir := "."
+		if m.filename != stdinName {
+			dir = filepath.Dir(m.filename)
+		}
+		path = filepath.Join(dir, path)
+	}
+	if fi, err := os.Stat(path); err != nil || !fi.Mode().IsRegular() {
+		return "", "", false
+	}
+	return path, frag, true
 }
 
-// ---------- indexing (restored) ----------
-
-func (m *model) buildIndexes() {
-	// Build indexes from the CURRENT visible content (post-effects stripped),
-	// so anchors/links scroll to what the user actually sees right now.
-	plain := stripANSI(strings.Join(m.renderedLines, "\n"))
+// showDocument displays doc from the top, restarting the stream.
+func (m *model) showDocument(doc document) {
+	m.filename = doc.name
+	m.rawMarkdown = doc.raw
+	m.section = ""       // other files and links are shown whole
+	m.tasksDirty = false // unsaved toggles go with the text they were in
+	m.words = countWords(m.markdown())
+	m.cache.valid = false
+	m.fileMod = doc.mod
+	m.fileSize = doc.size
+	m.linkIndex = -1
+	m.taskIndex = -1
+	m.xOffset = 0
+	m.readMark = 0
+	m.loadBookmarks()
+
+	// each file gets its own baud animation
+	m.txStart = time.Now()
+	m.txLastAvail = 0
+	m.txBytesAvailable = 0
+	m.streamDone = false
+	m.paused = false
+	m.animating = false
+	m.view.GotoTop()
+	m.recalcRendered(m.view.Width, m.screenHeight())
+	m.rxBlink = 6
+}
 
-	m.headings = nil
-	for _, mm := range reHeading.FindAllStringSubmatch(m.rawMarkdown, -1) {
-		txt := strings.TrimSpace(mm[1])
-		if txt == "" {
-			continue
+// reloadFile re-reads the current file in place, keeping the scroll position.
+func (m *model) reloadFile() {
+	doc, err := readDocument(m.filename, m.encoding)
+	if err != nil {
+		// often a half-written save; the next change will retry
+		m.flash(err.Error())
+		return
+	}
+	off, wasBroken := m.view.YOffset, m.err != nil
+	m.rawMarkdown = doc.raw
+	m.tasksDirty = false // the file as it is now wins
+	m.words = countWords(m.markdown())
+	m.cache.valid = false
+	m.fileMod = doc.mod
+	m.fileSize = doc.size
+	m.recalcRendered(m.view.Width, m.screenHeight())
+	if m.err != nil {
+		if wasBroken {
+			m.flash("still failing")
 		}
-		anc := slugify(txt)
-		idx := indexLineOf(plain, txt)
-		m.headings = append(m.headings, heading{text: txt, anchor: anc, renderedLine: idx})
+		return
 	}
+	m.view.SetYOffset(clamp(off, 0, max(0, m.totalLines-m.view.Height)))
+	m.rxBlink = 6
+	m.flash("reloaded")
+}
 
-	m.links = nil
-	for _, mm := range reLink.FindAllStringSubmatchIndex(m.rawMarkdown, -1) {
-		text := m.rawMarkdown[mm[2]:mm[3]]
-		dest := m.rawMarkdown[mm[4]:mm[5]]
-		needle := dest
-		if strings.HasPrefix(dest, "#") {
-			needle = text
+// reload is reloadFile as a leave for confirmLeave.
+func (m *model) reload() tea.Cmd {
+	m.reloadFile()
+	return m.tick()
+}
+
+// appendFile takes in text appended to a followed file: what is on screen
+// stays, and only the new tail streams in at the baud rate. Anything other
+// than growth is handled as a normal reload.
+func (m *model) appendFile() {
+	doc, err := readDocument(m.filename, m.encoding)
+	if err != nil {
+		m.flash(err.Error())
+		return
+	}
+	if !strings.HasPrefix(doc.raw, m.rawMarkdown) {
+		m.reloadFile()
+		return
+	}
+	atBottom := m.view.AtBottom()
+	m.rawMarkdown = doc.raw
+	m.words = countWords(m.markdown())
+	m.cache.valid = false
+	m.fileMod = doc.mod
+	m.fileSize = doc.size
+	if m.bytesPerSecond > 0 {
+		// restart the clock so the bytes already shown count as sent
+		ref := time.Now()
+		if m.paused {
+			ref = m.pausedAt
 		}
-		idx := indexLineOf(plain, needle)
-		m.links = append(m.links, link{text: text, target: dest, renderedLine: idx})
+		sent := time.Duration(float64(m.txBytesAvailable) / m.bytesPerSecond * float64(time.Second))
+		m.txStart = ref.Add(-sent)
+		m.streamDone = false
 	}
-	if len(m.links) == 0 {
-		m.linkIndex = -1
-	} else if m.linkIndex >= len(m.links) {
-		m.linkIndex = len(m.links) - 1
+	m.recalcRendered(m.view.Width, m.screenHeight())
+	if atBottom {
+		m.view.GotoBottom()
 	}
+	m.rxBlink = 6
 }
 
-func indexLineOf(haystack, needle string) int {
-	if needle == "" {
-		return -1
+// pauseAutoscroll stops auto-scrolling when the reader scrolls by hand;
+// a starts it again.
+func (m *model) pauseAutoscroll() {
+	if m.autoscroll {
+		m.autoscroll = false
+		m.flash("auto-scroll: paused (a resumes)")
 	}
-	pos := strings.Index(haystack, needle)
-	if pos < 0 {
-		return -1
-	}
-	return bytes.Count([]byte(haystack[:pos]), []byte("\n"))
 }
 
-// ---------- view ----------
+// quit ends the program, leaving the last frame for --no-altscreen.
+func (m *model) quit() tea.Cmd {
+	m.quitting = true
+	return tea.Quit
+}
 
-func (m model) View() string {
-	if m.err != nil {
-		return fmt.Sprintf("error: %v\n", m.err)
+// finalView is what --no-altscreen leaves in the scrollback: the text
+// that was on screen, without the header, footer or anything open over it.
+func (m model) finalView() string {
+	lines := strings.Split(m.view.View(), "\n")
+	for len(lines) > 0 && strings.TrimSpace(stripANSI(lines[len(lines)-1])) == "" {
+		lines = lines[:len(lines)-1]
 	}
-	w := m.view.Width
-	if w <= 0 {
-		w = 80
+	return strings.Join(lines, "\n")
+}
+
+// handleAction runs a key action; ok is false when a is not handled here,
+// letting the key fall through to the viewport.
+func (m *model) handleAction(a action) (cmd tea.Cmd, ok bool) {
+	switch a {
+	case actScrollUp, actScrollDown, actPageUp, actPageDown, actHalfPageUp, actHalfPageDown, actTop, actBottom:
+		m.pauseAutoscroll()
+	}
+	switch a {
+	case actQuit:
+		return m.confirmQuit(), true
+
+	// Smooth single-line scrolling via animator
+	case actScrollUp:
+		m.txBlink = 6
+		return m.startScrollTo(m.view.YOffset - 1), true
+	case actScrollDown:
+		m.txBlink = 6
+		return m.startScrollTo(m.view.YOffset + 1), true
+
+	// Smooth page scrolling via animator
+	case actPageUp:
+		m.txBlink = 6
+		return m.startScrollTo(m.view.YOffset - m.view.Height), true
+	case actPageDown:
+		m.txBlink = 6
+		return m.startScrollTo(m.view.YOffset + m.view.Height), true
+	case actHalfPageUp:
+		m.txBlink = 6
+		return m.startScrollTo(m.view.YOffset - max(1, m.view.Height/2)), true
+	case actHalfPageDown:
+		m.txBlink = 6
+		return m.startScrollTo(m.view.YOffset + max(1, m.view.Height/2)), true
+
+	case actTop:
+		m.txBlink = 6
+		m.view.GotoTop()
+		return nil, true
+	case actBottom:
+		m.txBlink = 6
+		m.view.GotoBottom()
+		return nil, true
+
+	case actPause:
+		if m.taskOnScreen() {
+			m.toggleTask()
+			return m.tick(), true
+		}
+		if m.streamDone || m.bytesPerSecond <= 0 {
+			return nil, true
+		}
+		m.togglePause()
+		return m.tick(), true
+	case actSkipStream:
+		m.skipStream()
+		return nil, true
+	case actBaud300, actBaud1200, actBaud9600, actBaud57600, actBaud115200:
+		m.setBaud(baudPresets[a])
+		return nil, true
+
+	case actNextTask:
+		m.selectTask(1)
+		return m.tick(), true
+	case actNextChange:
+		m.jumpHunk(1)
+		return m.tick(), true
+	case actPrevChange:
+		m.jumpHunk(-1)
+		return m.tick(), true
+	case actPrevTask:
+		m.selectTask(-1)
+		return m.tick(), true
+
+	case actNextLink:
+		if len(m.links) > 0 {
+			m.txBlink = 6
+			if m.linkIndex == -1 {
+				m.linkIndex = 0
+			} else {
+				m.linkIndex = (m.linkIndex + 1) % len(m.links)
+			}
+			m.scrollToLink()
+		}
+		return nil, true
+	case actPrevLink:
+		if len(m.links) > 0 {
+			m.txBlink = 6
+			if m.linkIndex == -1 {
+				m.linkIndex = len(m.links) - 1
+			} else {
+				m.linkIndex = (m.linkIndex - 1 + len(m.links)) % len(m.links)
+			}
+			m.scrollToLink()
+		}
+		return nil, true
+	case actFollowLink:
+		m.txBlink = 6
+		if m.linkIndex >= 0 && m.linkIndex < len(m.links) {
+			return m.followLink(m.links[m.linkIndex]), true
+		}
+		m.skipStream()
+		return nil, true
+	case actCopyLink:
+		if m.linkIndex >= 0 && m.linkIndex < len(m.links) {
+			m.txBlink = 6
+			if err := clipboard.WriteAll(m.links[m.linkIndex].target); err != nil {
+				m.flash("clipboard unavailable")
+			} else {
+				m.flash("copied")
+			}
+			return m.tick(), true
+		}
+		return nil, true
+
+	case actSearch:
+		m.mode, m.input = modeSearch, ""
+		return nil, true
+	case actGotoLine:
+		m.mode, m.input = modeGoto, ""
+		return nil, true
+	case actSetBookmark:
+		m.mode, m.input = modeMark, ""
+		return nil, true
+	case actJumpBookmark:
+		m.mode, m.input = modeJump, ""
+		return nil, true
+	case actNextMatch:
+		m.txBlink = 6
+		m.nextMatch(1)
+		return nil, true
+	case actPrevMatch:
+		m.txBlink = 6
+		m.nextMatch(-1)
+		return nil, true
+
+	case actToc:
+		if len(m.headings) > 0 {
+			m.mode = modeToc
+			m.tocIndex = m.currentHeading()
+		}
+		return nil, true
+
+	case actHelp:
+		m.mode = modeHelp
+		m.helpOffset = 0
+		return nil, true
+	case actInfo:
+		m.mode = modeInfo
+		return nil, true
+
+	case actBack:
+		if n := len(m.back); n > 0 && m.otherDocument(m.back[n-1]) {
+			return m.confirmLeave(func(m *model) tea.Cmd { m.goBack(); return m.tick() }), true
+		}
+		m.goBack()
+		return m.tick(), true
+	case actForward:
+		if n := len(m.forward); n > 0 && m.otherDocument(m.forward[n-1]) {
+			return m.confirmLeave(func(m *model) tea.Cmd { m.goForward(); return m.tick() }), true
+		}
+		m.goForward()
+		return m.tick(), true
+
+	case actNextFile, actPrevFile:
+		if len(m.files) > 1 {
+			step := 1
+			if a == actPrevFile {
+				step = -1
+			}
+			i := (m.fileIndex + step + len(m.files)) % len(m.files)
+			return m.confirmLeave(func(m *model) tea.Cmd { m.loadFile(i); return m.tick() }), true
+		}
+		return nil, true
+
+	case actWrapWider:
+		m.adjustWrap(wrapStep)
+		return m.tick(), true
+	case actWrapNarrower:
+		m.adjustWrap(-wrapStep)
+		return m.tick(), true
+	case actPanLeft, actPanRight:
+		step := panStep
+		if a == actPanLeft {
+			step = -step
+		}
+		m.pan(step)
+		return nil, true
+	case actWrapMode:
+		m.wrapMode = (m.wrapMode + 1) % (wrapNone + 1)
+		m.xOffset = 0
+		m.rewrap()
+		m.flash("wrap: " + m.wrapMode.String())
+		return m.tick(), true
+	case actCycleStyle:
+		if m.noColor {
+			return nil, true
+		}
+		m.cycleStyle()
+		return m.tick(), true
+	case actAutoscroll:
+		m.autoscroll = !m.autoscroll
+		m.autoscrollAcc = 0
+		if m.autoscroll {
+			m.flash(fmt.Sprintf("auto-scroll: %g lines/s", m.autoscrollSpeed))
+		} else {
+			m.flash("auto-scroll: off")
+		}
+		return m.tick(), true
+	case actEdit:
+		return m.editSource(), true
+	case actReload:
+		if m.filename == stdinName {
+			m.flash("stdin can't be reloaded")
+			return m.tick(), true
+		}
+		return m.confirmLeave((*model).reload), true
+	case actMinimal:
+		h := m.screenHeight()
+		m.minimal = !m.minimal
+		m.recalcRendered(m.view.Width, h)
+		m.view.SetYOffset(m.view.YOffset) // re-clamp to the new height
+		return nil, true
+	case actHighlightLinks:
+		m.highlightLinks = !m.highlightLinks
+		m.refreshContent()
+		return nil, true
+	case actRedraw:
+		// stale cells left by tmux or a flaky link: rebuild the content,
+		// effects included, and have Bubble Tea repaint every line
+		m.refreshContent()
+		return tea.ClearScreen, true
+	case actLineNumbers:
+		m.lineNumbers = !m.lineNumbers
+		m.rewrap()
+		return nil, true
+	case actDimRead:
+		m.dimRead = !m.dimRead
+		m.readMark = m.view.YOffset
+		m.refreshContent()
+		return nil, true
+
+	case actToggleScanlines:
+		if m.noColor {
+			return nil, true
+		}
+		m.scanlines = !m.scanlines
+		m.rxBlink = 6
+		m.recalcRendered(m.view.Width, m.screenHeight())
+		return nil, true
+	case actInverse:
+		if m.noColor {
+			return nil, true
+		}
+		m.inverse = !m.inverse
+		m.recalcRendered(m.view.Width, m.screenHeight())
+		return nil, true
+	case actToggleMono:
+		if m.noColor {
+			return nil, true
+		}
+		m.mono++
+		if m.mono > monoBlue {
+			m.mono = monoOff
+		}
+		m.rxBlink = 6
+		m.recalcRendered(m.view.Width, m.screenHeight())
+		return nil, true
+	case actToggleBBS:
+		m.bbsChrome = !m.bbsChrome
+		m.rxBlink = 6
+		m.recalcRendered(m.view.Width, m.screenHeight())
+		return nil, true
+	case actDegauss:
+		m.degauss = m.degaussTotalFrames()
+		m.rxBlink, m.txBlink = 12, 12
+		return m.tick(), true
+	case actToggleCursor:
+		m.cursor = !m.cursor
+		m.rxBlink = 6
+		return m.tick(), true
 	}
+	return nil, false
+}
 
-	// Right side: file mod time (ISO 8601) + human size + caps
-	caps := "TC"
-	if !m.truecolor && m.palette256 {
-		caps = "256"
+// handleMouse scrolls on the wheel and follows a link on left click.
+func (m *model) handleMouse(ev tea.MouseEvent) tea.Cmd {
+	if ev.Action != tea.MouseActionPress {
+		return nil
 	}
-	if !m.truecolor && !m.palette256 {
-		caps = "16"
+	switch ev.Button {
+	case tea.MouseButtonWheelUp:
+		m.pauseAutoscroll()
+		return m.startScrollTo(m.targetOrOffset() - 3)
+	case tea.MouseButtonWheelDown:
+		m.pauseAutoscroll()
+		return m.startScrollTo(m.targetOrOffset() + 3)
+	case tea.MouseButtonLeft:
+		line := ev.Y - m.view.YPosition + m.view.YOffset
+		if i := m.linkAt(line, ev.X-m.gutter-m.margin()+m.xOffset); i >= 0 {
+			m.txBlink = 6
+			m.linkIndex = i
+			return m.followLink(m.links[i])
+		}
 	}
+	return nil
+}
 
-	right := fmt.Sprintf("%s %s [%s]", m.fileMod.Format(time.RFC3339), humanSize(m.fileSize), caps)
+// targetOrOffset is where the view is heading: the animation target while
+// scrolling, else the current offset. Lets repeated wheel ticks accumulate.
+func (m *model) targetOrOffset() int {
+	if m.animating {
+		return m.targetOffset
+	}
+	return m.view.YOffset
+}
 
-	left := m.filename
-	available := w - displayWidth(right) - 1
-	if available < 1 {
-		available = 1
+// linkAt finds the link on rendered line whose text or URL spans column x,
+// falling back to the first link on that line; -1 if none.
+func (m *model) linkAt(line, x int) int {
+	if line < 0 || line >= len(m.renderedLines) {
+		return -1
+	}
+	plain := stripANSI(m.renderedLines[line])
+	first := -1
+	for i, l := range m.links {
+		if l.renderedLine != line {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		for _, needle := range []string{l.text, l.target} {
+			if p := strings.Index(plain, needle); p >= 0 && needle != "" {
+				col := displayWidth(plain[:p])
+				if x >= col && x < col+displayWidth(needle) {
+					return i
+				}
+			}
+		}
 	}
-	left = truncateToWidth(left, available)
+	return first
+}
 
-	// Mode indicators
-	badges := []string{}
-	if m.fixed8025 {
-		badges = append(badges, "80x25")
+func (m *model) scrollToLink() {
+	if m.linkIndex < 0 || m.linkIndex >= len(m.links) {
+		return
 	}
-	if m.scanlines {
-		badges = append(badges, "Scanlines")
+	if m.highlightLinks {
+		m.refreshContent() // move the inverse video to the new selection
 	}
-	if m.mono != monoOff {
-		badges = append(badges, "Mono:"+m.mono.String())
+	line := m.links[m.linkIndex].renderedLine
+	if line < 0 {
+		return
+	}
+	if line < m.view.YOffset || line >= m.view.YOffset+m.view.Height {
+		m.record() // only a jump when the link was off screen
 	}
-	if m.bbsChrome {
-		badges = append(badges, "BBS")
+	target := line - m.view.Height/2
+	if target < 0 {
+		target = 0
 	}
-	if m.baudrate > 0 && !m.streamDone {
-		badges = append(badges, fmt.Sprintf("RX %.0fB/s", m.bytesPerSecond))
+	if target > m.totalLines-m.view.Height {
+		target = m.totalLines - m.view.Height
 	}
-	if len(badges) > 0 {
-		left = left + "  [" + strings.Join(badges, " | ") + "]"
+	if target < 0 {
+		target = 0
 	}
+	m.view.SetYOffset(target)
+}
 
-	header := fmt.Sprintf("%-*s %s", available, left, right)
-
-	// current line = last visible line, capped at total
-	current := m.view.YOffset + m.view.Height
-	if current > m.totalLines {
-		current = m.totalLines
+// followLink jumps to an in-document anchor or hands an external URL to
+// the browser; a launch error comes back as an openFailedMsg.
+func (m *model) followLink(l link) tea.Cmd {
+	dest := strings.TrimSpace(l.target)
+	if dest == "" {
+		return nil
 	}
-	if current < 1 && m.totalLines > 0 {
-		current = 1
+	if strings.HasPrefix(dest, "#") {
+		m.record()
+		m.jumpToAnchor(strings.TrimPrefix(dest, "#"), l.renderedLine)
+		return nil
 	}
-	total := max(1, m.totalLines)
-
-	// progress ratio based on scroll offset (start 0, end 1 at bottom)
-	ratio := 0.0
-	den := float64(max(1, m.totalLines-m.view.Height))
-	if den > 0 {
-		ratio = float64(m.view.YOffset) / den
-		if ratio < 0 {
-			ratio = 0
+	if path, frag, ok := m.localDocument(dest); ok {
+		doc, err := readDocument(path, m.encoding)
+		if err != nil {
+			m.flash(err.Error())
+			return m.tick()
 		}
-		if ratio > 1 {
-			ratio = 1
-		}
-	}
-	progress := drawProgressBar(w, ratio, fmt.Sprintf(" %d / %d ", current, total))
-
-	footer := progress
-	if m.bbsChrome {
-		footer = m.bbsStatusLine(w)
+		return m.confirmLeave(func(m *model) tea.Cmd {
+			m.record()
+			m.showDocument(doc)
+			if frag != "" {
+				// anchors need the whole document on screen
+				m.skipStream()
+				m.jumpToAnchor(frag, -1)
+			}
+			return m.tick()
+		})
 	}
-
-	return header + "\n" + m.view.View() + "\n" + footer
+	return openURL(dest, m.browser)
 }
 
-func (m model) bbsStatusLine(w int) string {
-	// e.g., " CONNECT 115200  RX:· TX:·  [s]canlines [m]ono [b]bs [d]egauss  [q]uit "
-	rx := "·"
-	tx := "·"
-	if m.rxBlink > 0 {
-		rx = "●"
+// jumpToAnchor scrolls to the heading or footnote named anc, else to
+// fallback when that is a rendered line; it reports whether anc was found.
+func (m *model) jumpToAnchor(anc string, fallback int) bool {
+	// exact anchors first, so #setup-1 reaches the second "Setup"
+	// rather than a loose match on an earlier heading
+	for _, loose := range []bool{false, true} {
+		for _, h := range m.headings {
+			if h.term {
+				continue
+			}
+			if h.anchor == anc || loose && (slugify(h.text) == anc || slugify(anc) == h.anchor) {
+				if h.renderedLine >= 0 {
+					m.view.SetYOffset(clamp(h.renderedLine, 0, max(0, m.totalLines-m.view.Height)))
+					return true
+				}
+			}
+		}
 	}
-	if m.txBlink > 0 {
-		tx = "●"
+	if line, ok := m.footnotes[strings.TrimPrefix(anc, "fn-")]; ok && strings.HasPrefix(anc, "fn-") && line >= 0 {
+		m.view.SetYOffset(clamp(line, 0, max(0, m.totalLines-m.view.Height)))
+		return true
 	}
-	label := fmt.Sprintf(" CONNECT %d  RX:%s TX:%s  [s]canlines [m]ono [b]bs [d]egauss  [q]uit ", m.baudrate, rx, tx)
-	if displayWidth(label) >= w {
-		return truncateToWidth(label, w)
+	if fallback >= 0 {
+		m.view.SetYOffset(clamp(fallback, 0, max(0, m.totalLines-m.view.Height)))
 	}
-	pad := strings.Repeat(" ", w-displayWidth(label))
-	return label + pad
+	return false
 }
 
-func drawProgressBar(width int, ratio float64, label string) string {
-	if width < 3 {
-		return strings.Repeat("█", width)
-	}
-	fill := int(float64(width) * ratio)
-	if fill < 0 {
-		fill = 0
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
 	}
-	if fill > width {
-		fill = width
+	if v > hi {
+		return hi
 	}
-	var b strings.Builder
-	b.Grow(width)
-	b.WriteString(strings.Repeat("█", fill))
-	if fill < width {
-		b.WriteString(strings.Repeat("░", width-fill))
+	return v
+}
+func max(a, b int) int {
+	if a > b {
+		return a
 	}
-	bar := b.String()
+	return b
+}
 
-	if len(label) > 0 && len(label) < width {
-		start := (width - len(label)) / 2
-		runes := []rune(bar)
-		labelRunes := []rune(label)
-		for i := 0; i < len(labelRunes) && start+i < len(runes); i++ {
-			runes[start+i] = labelRunes[i]
-		}
-		bar = string(runes)
+// ---------- util ----------
+
+// timeFormat is how the header shows the file's modification time, --time.
+type timeFormat int
+
+const (
+	timeISO      timeFormat = iota // RFC 3339
+	timeRelative                   // 3 hours ago
+	timeNone                       // not at all
+)
+
+func (f timeFormat) format(t, now time.Time) string {
+	switch f {
+	case timeRelative:
+		return relativeTime(t, now)
+	case timeNone:
+		return ""
+	default:
+		return t.Format(time.RFC3339)
 	}
-	return bar
 }
 
-func displayWidth(s string) int {
-	return utf8.RuneCountInString(s)
-}
+// clockMsg is the header's relative file time ("3 minutes ago") due to
+// change. The ticker stops when nothing moves, so it needs its own.
+type clockMsg struct{}
 
-// truncateVisibleToWidth truncates by visible width (ANSI-safe) for simple UI strings we control.
-func truncateVisibleToWidth(s string, w int) string {
-	plain := stripANSI(s)
-	if displayWidth(plain) <= w {
-		return s
+// clockTick waits for the relative file time to next change, on the
+// minute since the file's modification; nil unless --time relative.
+func (m *model) clockTick() tea.Cmd {
+	if m.timeFormat != timeRelative {
+		return nil
 	}
-	runes := []rune(plain)
-	return string(runes[:w])
+	return tea.Tick(untilNextMinute(m.fileMod, time.Now()), func(time.Time) tea.Msg { return clockMsg{} })
 }
 
-func truncateToWidth(s string, w int) string {
-	if displayWidth(s) <= w {
-		return s
-	}
-	runes := []rune(s)
-	return string(runes[:w])
+// untilNextMinute is how long after now a whole number of minutes will
+// have passed since t.
+func untilNextMinute(t, now time.Time) time.Duration {
+	return time.Minute - ((now.Sub(t)%time.Minute)+time.Minute)%time.Minute
 }
 
-// ---------- util ----------
+// relativeTime says how long before now t was, in its largest unit.
+func relativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	if d < 0 {
+		return "in the future"
+	}
+	units := []struct {
+		size time.Duration
+		name string
+	}{
+		{365 * 24 * time.Hour, "year"},
+		{30 * 24 * time.Hour, "month"},
+		{7 * 24 * time.Hour, "week"},
+		{24 * time.Hour, "day"},
+		{time.Hour, "hour"},
+		{time.Minute, "minute"},
+	}
+	for _, u := range units {
+		if n := int(d / u.size); n >= 1 {
+			if n == 1 {
+				return "1 " + u.name + " ago"
+			}
+			return fmt.Sprintf("%d %ss ago", n, u.name)
+		}
+	}
+	return "just now"
+}
 
 func humanSize(n int64) string {
 	u := []string{"B", "KB", "MB", "GB", "TB", "PB"}
@@ -890,84 +1521,165 @@ func humanSize(n int64) string {
 	return fmt.Sprintf("%.2f%s", f, u[i])
 }
 
-// monochrome color sequences (prefer truecolor; fall back to 256/16-color)
-func monoSGR(m monoMode, truecolor, palette256 bool) (open, close string) {
-	var fg string
-	switch m {
-	case monoGreen:
-		fg = "32"
-	case monoAmber:
-		fg = "33"
-	case monoWhite:
-		fg = "37"
-	default:
-		return "", ""
-	}
-	if palette256 {
-		switch m {
-		case monoGreen:
-			fg = "38;5;82"
-		case monoAmber:
-			fg = "38;5;214"
-		case monoWhite:
-			fg = "38;5;252"
+// ---------- flags ----------
+
+type startFlags struct {
+	style           string
+	styleDark       string
+	styleLight      string
+	wrap            int
+	maxWidth        int
+	clampWrap       bool
+	scanlines       bool
+	inverse         bool
+	mono            monoMode
+	fixed8025       bool
+	bbs             bool
+	baudrate        int
+	watch           bool
+	follow          bool
+	print           bool
+	toc             bool
+	json            bool
+	keys            keymap
+	osc8            bool
+	noMouse         bool
+	noAltScreen     bool
+	exportHTML      string
+	rec             string
+	strict          bool
+	startAt         string
+	cursor          bool
+	phosphor        bool
+	bloom           bool
+	degaussStrength int
+	codeTheme       string
+	preserveBreaks  bool
+	renderTimeout   time.Duration
+	quoteBar        rune
+	quoteColors     []string
+	noColor         bool
+	forceColor      bool
+	configPath      string
+	lineNumbers     bool
+	highlightLinks  bool
+	minimal         bool
+	handshake       bool
+	loop            bool
+	listStyles      bool
+	encoding        string
+	raw             bool
+	tabWidth        int
+	editTasks       bool
+	diff            bool
+	frontMatter     frontMatterMode
+	navDefs         bool
+	section         string
+	pager           string
+	fps             int
+	autoscrollSpeed float64
+	wrapMode        wrapMode
+	dimRead         bool
+	browser         string
+	cols            int
+	scroll          scrollEasing
+	progress        progressLabel
+	barChars        barChars
+	timeFormat      timeFormat
+	color           colorLevel
+}
+
+// ---------- input ----------
+
+const stdinName = "<stdin>"
+
+// localFile reports whether the document is a file on disk, not stdin or
+// a URL.
+func (m *model) localFile() bool { return m.filename != stdinName && !isURL(m.filename) }
+
+type document struct {
+	name string // absolute path, http(s) URL, or stdinName
+	raw  string
+	mod  time.Time
+	size int64
+}
+
+// loadDocument reads the first file named in args, or stdin when no file is
+// given, decoding it from enc (see decodeText).
+func loadDocument(args []string, enc string) (document, error) {
+	if len(args) == 0 {
+		if isatty.IsTerminal(os.Stdin.Fd()) || isatty.IsCygwinTerminal(os.Stdin.Fd()) {
+			return document{}, errors.New("no input: pass a file or pipe Markdown on stdin")
 		}
-	}
-	if truecolor {
-		switch m {
-		case monoGreen:
-			fg = "38;2;0;255;128"
-		case monoAmber:
-			fg = "38;2;255;176;0"
-		case monoWhite:
-			fg = "38;2;230;230;230"
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return document{}, err
+		}
+		raw, err := decodeText(b, stdinName, enc)
+		if err != nil {
+			return document{}, err
 		}
+		return document{name: stdinName, raw: raw, mod: time.Now(), size: int64(len(b))}, nil
 	}
-	return "\x1b[" + fg + "m", "\x1b[0m"
+
+	return readDocument(args[0], enc)
+}
+
+// isFile reports whether path names a regular file.
+func isFile(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.Mode().IsRegular()
 }
 
-// crude capability detection (best-effort)
-func detectColorCaps() (truecolor bool, palette256 bool) {
-	tc := os.Getenv("COLORTERM")
-	if strings.Contains(strings.ToLower(tc), "truecolor") || strings.Contains(strings.ToLower(tc), "24bit") {
-		truecolor = true
+// readDocument reads a Markdown file along with its metadata.
+func readDocument(path, enc string) (document, error) {
+	if isURL(path) {
+		return fetchDocument(path, enc)
 	}
-	termVar := strings.ToLower(os.Getenv("TERM"))
-	if strings.Contains(termVar, "256color") || strings.Contains(termVar, "xterm") || strings.Contains(termVar, "screen-256color") {
-		palette256 = true
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return document{}, err
 	}
-	if truecolor {
-		palette256 = true
+	abs, _ := filepath.Abs(path)
+	raw, err := decodeText(b, path, enc)
+	if err != nil {
+		return document{}, fmt.Errorf("%s: %w", path, err)
 	}
-	return
-}
 
-// ---------- openURL ----------
-
-func openURL(u string) error {
-	var cmd *exec.Cmd
-	switch runtime.GOOS {
-	case "darwin":
-		cmd = exec.Command("open", u)
-	case "windows":
-		cmd = exec.Command("cmd", "/c", "start", u)
-	default:
-		cmd = exec.Command("xdg-open", u)
+	// file metadata
+	fi, err := os.Stat(path)
+	if err != nil {
+		return document{}, err
 	}
-	cmd.Stdout, cmd.Stderr, cmd.Stdin = nil, nil, nil
-	return cmd.Start()
+	return document{name: abs, raw: raw, mod: fi.ModTime(), size: fi.Size()}, nil
 }
 
-// ---------- flags ----------
+// ---------- outline ----------
 
-type startFlags struct {
-	style     string
-	wrap      int
-	scanlines bool
-	mono      monoMode
-	fixed8025 bool
-	bbs       bool
-	baudrate  int
+type tocEntry struct {
+	Level  int    `json:"level"`
+	Text   string `json:"text"`
+	Anchor string `json:"anchor"`
+}
+
+// printTOC writes the heading outline of raw, as indented text or JSON.
+func printTOC(w io.Writer, raw string, asJSON bool) error {
+	hs := parseHeadings(raw, false)
+	if asJSON {
+		entries := make([]tocEntry, 0, len(hs))
+		for _, h := range hs {
+			entries = append(entries, tocEntry{Level: h.level, Text: h.text, Anchor: h.anchor})
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+	for _, h := range hs {
+		if _, err := fmt.Fprintf(w, "%s%s (#%s)\n", strings.Repeat("  ", h.level-1), h.text, h.anchor); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // ---------- cobra CLI ----------
@@ -978,55 +1690,231 @@ func main() {
 	flags.baudrate = 115200
 
 	cmd := &cobra.Command{
-		Use:   "mdnfo <file.md>",
+		Use:   "mdnfo [file.md ...]",
 		Short: "Old-school NFO-style Markdown viewer (terminal-only)",
-		Args:  cobra.ExactArgs(1),
+		Long: "Old-school NFO-style Markdown viewer (terminal-only).\n\n" +
+			"With no file argument, the document is read from stdin.\n" +
+			"With several files, use [ and ] to switch between them.\n\n" +
+			"Key actions (remap under [keys] in " + defaultConfigPath() + "):\n" + keyHelp(),
+		Args: cobra.ArbitraryArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			path := args[0]
-			b, err := os.ReadFile(path)
+			if flags.listStyles {
+				return printStyles(os.Stdout)
+			}
+			doc, err := loadDocument(args, flags.encoding)
 			if err != nil {
 				return err
 			}
-			if !isatty.IsTerminal(os.Stdout.Fd()) && !isatty.IsCygwinTerminal(os.Stdout.Fd()) {
-				return errors.New("stdout is not a TTY (refusing to render ANSI output)")
+			var base document
+			if flags.diff {
+				// show the new file, compared against the old one
+				base = doc
+				if doc, err = readDocument(args[1], flags.encoding); err != nil {
+					return err
+				}
+				args = args[1:]
 			}
-			abs, _ := filepath.Abs(path)
-
-			// file metadata
-			fi, err := os.Stat(path)
+			if flags.section != "" {
+				if _, ok := sectionBody(markdownBody(doc.raw, flags.frontMatter), flags.section); !ok {
+					return fmt.Errorf("%s: no section %q (--toc lists the anchors)", doc.name, flags.section)
+				}
+			}
+			if flags.strict {
+				// every file named, not just the one shown
+				docs := []document{doc}
+				for _, name := range args[min(1, len(args)):] {
+					d, err := readDocument(name, flags.encoding)
+					if err != nil {
+						return err
+					}
+					docs = append(docs, d)
+				}
+				for i := range docs {
+					docs[i].raw = markdownBody(docs[i].raw, flags.frontMatter)
+				}
+				if err := checkAnchors(os.Stderr, docs); err != nil {
+					return err
+				}
+			}
+			if flags.toc {
+				return printTOC(os.Stdout, sectionOf(markdownBody(doc.raw, flags.frontMatter), flags.section), flags.json)
+			}
+			if flags.exportHTML != "" {
+				body := doc
+				body.raw = sectionOf(markdownBody(doc.raw, flags.frontMatter), flags.section)
+				return exportHTMLFile(flags.exportHTML, body)
+			}
+			if flags.print || flags.pager != "" {
+				if blank(doc.raw) {
+					return fmt.Errorf("%s: empty document", doc.name)
+				}
+				if blank(markdownBody(doc.raw, flags.frontMatter)) {
+					return fmt.Errorf("%s: nothing but front matter (--front-matter raw renders it)", doc.name)
+				}
+				style := flags.style
+				if flags.forceColor {
+					style = colorStyle(flags)
+				}
+				m := initialModel(doc.name, doc.raw, style, flags.wrap, doc.mod, doc.size, flags)
+				m.compareWith(base)
+				w, _ := terminalSize()
+				out, err := m.renderPlain(w)
+				if err != nil {
+					return err
+				}
+				if m.renderTimedOut {
+					fmt.Fprintln(os.Stderr, "warning: "+m.renderTimeoutNote())
+				}
+				if flags.pager != "" && !flags.print {
+					return page(out, pagerCommand(flags.pager))
+				}
+				_, err = fmt.Fprintln(os.Stdout, out)
+				return err
+			}
+			tty := isatty.IsTerminal(os.Stdout.Fd()) || isatty.IsCygwinTerminal(os.Stdout.Fd())
+			if !tty && !flags.forceColor {
+				return errors.New("stdout is not a TTY (refusing to render ANSI output; --force-color renders anyway)")
+			}
+			configPath := flags.configPath
+			if configPath == "" {
+				configPath = defaultConfigPath()
+			}
+			cfg, err := loadConfig(configPath, flags.configPath != "")
 			if err != nil {
 				return err
 			}
+			if flags.keys, err = cfg.keymap(); err != nil {
+				return err
+			}
 
 			// create model
-			m := initialModel(abs, string(b), flags.style, flags.wrap, fi.ModTime(), fi.Size(), flags)
+			style := pickStyle(flags)
+			if !tty {
+				style = colorStyle(flags)
+			}
+			m := initialModel(doc.name, doc.raw, style, flags.wrap, doc.mod, doc.size, flags)
+			m.compareWith(base)
+			m.files = args
+			m.statePath = defaultStatePath()
+			m.loadBookmarks()
 
 			// size to the real terminal BEFORE starting Bubble Tea
-			w, h := 80, 24
-			if ww, hh, err := term.GetSize(int(os.Stdout.Fd())); err == nil && ww > 0 && hh > 0 {
-				w, h = ww, hh
-			}
+			w, h := terminalSize()
 
 			// first render and start streaming clock
 			m.txStart = time.Now()
+			m.handshakeStart = m.txStart
 			m.recalcRendered(w, h)
+			if flags.startAt != "" {
+				m.startAt(flags.startAt)
+			}
 
-			prog := tea.NewProgram(m, tea.WithAltScreen())
+			var opts []tea.ProgramOption
+			if !flags.noAltScreen {
+				opts = append(opts, tea.WithAltScreen())
+			}
+			if !flags.noMouse {
+				opts = append(opts, tea.WithMouseCellMotion())
+			}
+			var rec *recorder
+			if flags.rec != "" {
+				if rec, err = newRecorder(flags.rec, os.Stdout, doc.name); err != nil {
+					return err
+				}
+				opts = append(opts, tea.WithOutput(rec))
+			}
+			prog := tea.NewProgram(m, opts...)
 			_, err = prog.Run()
+			if rec != nil {
+				if rerr := rec.finish(); err == nil {
+					err = rerr
+				}
+			}
 			return err
 		},
 	}
 
-	cmd.Flags().StringVar(&flags.style, "style", "auto", "glamour style: auto, dark, light, notty, dracula, pink, or a JSON style file path")
-	cmd.Flags().IntVar(&flags.wrap, "wrap", 0, "wrap width (0 = auto to terminal width)")
-	cmd.Flags().BoolVar(&flags.scanlines, "scanlines", false, "enable CRT-like scanlines")
-	cmd.Flags().BoolVar(&flags.bbs, "bbs", false, "enable BBS-style status line")
-	cmd.Flags().BoolVar(&flags.fixed8025, "80x25", false, "force classic 80x25 canvas")
-	cmd.Flags().IntVar(&flags.baudrate, "baudrate", 9600, "modem baud rate (bits/sec), e.g., 1200, 9600, 115200, 256000")
-	var monoStr string
-	cmd.Flags().StringVar(&monoStr, "mono", "off", "monochrome CRT mode: off, green, amber, white")
-
-	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+	cmd.PersistentFlags().StringVar(&flags.configPath, "config", "", "config file to read instead of "+defaultConfigPath())
+	cmd.PersistentFlags().StringVar(&flags.style, "style", "auto", "glamour style name (see --list-styles) or a JSON style file path")
+	cmd.PersistentFlags().StringVar(&flags.styleDark, "style-dark", "dark", "style for --style auto when the terminal reports a dark background")
+	cmd.PersistentFlags().StringVar(&flags.styleLight, "style-light", "light", "style for --style auto when the terminal reports a light background")
+	cmd.PersistentFlags().BoolVar(&flags.listStyles, "list-styles", false, "print the available --style names and exit")
+	cmd.PersistentFlags().DurationVar(&flags.renderTimeout, "render-timeout", 10*time.Second, "give up rendering a document after this long and show its text unrendered (0 = no limit)")
+	cmd.PersistentFlags().BoolVar(&flags.preserveBreaks, "preserve-breaks", false, "keep the line breaks inside paragraphs as written, hard breaks included (poetry, addresses)")
+	cmd.PersistentFlags().StringVar(&flags.codeTheme, "code-theme", "", "chroma theme for fenced code blocks, e.g. monokai, github, dracula (default: from --style)")
+	var quoteCharStr string
+	cmd.PersistentFlags().StringVar(&quoteCharStr, "quote-char", "", "blockquote bar character, e.g. \"┃\" or \">\" (default: from --style)")
+	cmd.PersistentFlags().StringSliceVar(&flags.quoteColors, "quote-colors", nil, "blockquote bar colors by nesting depth, outermost first: 256-color numbers or #rrggbb, e.g. 39,170,214")
+	cmd.PersistentFlags().IntVar(&flags.wrap, "wrap", 0, "wrap width (0 = auto to terminal width)")
+	cmd.PersistentFlags().IntVar(&flags.maxWidth, "max-width", 100, "cap on the auto wrap width; wider terminals center the text (0 = no cap)")
+	cmd.PersistentFlags().BoolVar(&flags.clampWrap, "clamp-wrap", false, "narrow a --wrap wider than the terminal to fit it, instead of scrolling sideways")
+	cmd.PersistentFlags().IntVar(&flags.cols, "cols", 0, "canvas width in columns, ignoring the terminal's (0 = terminal width)")
+	cmd.PersistentFlags().BoolVar(&flags.dimRead, "dim-read", false, "dim the text above the furthest point you have scrolled to (toggle with D)")
+	cmd.PersistentFlags().BoolVar(&flags.highlightLinks, "highlight-links", false, "underline every link, the selected one in inverse video (toggle with L)")
+	cmd.PersistentFlags().BoolVar(&flags.lineNumbers, "line-numbers", false, "show rendered line numbers in a left gutter (toggle with l)")
+	cmd.PersistentFlags().BoolVar(&flags.minimal, "minimal", false, "hide the header and footer, giving the whole terminal to the text (toggle with z)")
+	cmd.PersistentFlags().BoolVar(&flags.noColor, "no-color", false, "disable all color and styling (also enabled by the NO_COLOR env var)")
+	cmd.PersistentFlags().BoolVar(&flags.forceColor, "force-color", false, "render ANSI color even when stdout is not a terminal, for capturing it (overrides NO_COLOR)")
+	cmd.PersistentFlags().BoolVar(&flags.scanlines, "scanlines", false, "enable CRT-like scanlines")
+	cmd.PersistentFlags().BoolVar(&flags.inverse, "inverse", false, "reverse video: dark text on a light block, or the mono color as the background (toggle with v)")
+	cmd.PersistentFlags().BoolVar(&flags.bbs, "bbs", false, "enable BBS-style status line")
+	cmd.PersistentFlags().BoolVar(&flags.fixed8025, "80x25", false, "force classic 80x25 canvas")
+	cmd.PersistentFlags().BoolVar(&flags.print, "print", false, "render once to stdout and exit (no TUI, no TTY required)")
+	cmd.PersistentFlags().StringVar(&flags.startAt, "start-at", "", "open at a heading anchor (installation, #setup-1), a line number or a percentage")
+	cmd.PersistentFlags().BoolVar(&flags.strict, "strict", false, "with --print or --toc, fail listing file:line for each #anchor link that matches no heading")
+	cmd.PersistentFlags().StringVar(&flags.pager, "pager", "", "render once and page it with this command instead of the viewer (bare --pager: $PAGER, then less -R)")
+	cmd.PersistentFlags().Lookup("pager").NoOptDefVal = "auto"
+	cmd.PersistentFlags().BoolVar(&flags.toc, "toc", false, "print the heading outline and exit (no TUI, no TTY required)")
+	cmd.PersistentFlags().BoolVar(&flags.json, "json", false, "with --toc, print the outline as a JSON array of {level,text,anchor}")
+	cmd.PersistentFlags().BoolVar(&flags.osc8, "osc8", false, "emit OSC 8 hyperlinks so links are clickable in capable terminals")
+	cmd.PersistentFlags().StringVar(&flags.browser, "browser", "", "command that opens external links, %s = URL (default: $BROWSER, then the OS opener)")
+	cmd.PersistentFlags().BoolVar(&flags.noMouse, "no-mouse", false, "disable mouse support (keeps the terminal's own text selection)")
+	cmd.PersistentFlags().BoolVar(&flags.noAltScreen, "no-altscreen", false, "draw in the normal screen, not the alternate one, leaving the last page in the scrollback on quit")
+	cmd.PersistentFlags().StringVar(&flags.exportHTML, "export-html", "", "write the document as standalone HTML to `file` (- for stdout) and exit")
+	cmd.PersistentFlags().StringVar(&flags.rec, "rec", "", "record the session, streaming and effects included, as an asciinema v2 cast to `file`")
+	cmd.PersistentFlags().BoolVar(&flags.cursor, "cursor", false, "show a blinking block cursor")
+	cmd.PersistentFlags().BoolVar(&flags.phosphor, "phosphor", false, "phosphor afterglow while scrolling (always on in --mono modes)")
+	cmd.PersistentFlags().IntVar(&flags.degaussStrength, "degauss-strength", 1, "degauss (d) intensity, 1-3; 2 and 3 shake longer and wobble colors")
+	cmd.PersistentFlags().BoolVar(&flags.bloom, "bloom", false, "in --mono modes on truecolor terminals, make dense text glow brighter")
+	cmd.PersistentFlags().BoolVar(&flags.raw, "raw", false, "show the file verbatim without Markdown rendering (automatic for .nfo, .diz, .ans)")
+	cmd.PersistentFlags().IntVar(&flags.tabWidth, "tab-width", 4, "expand tabs to stops this many columns apart (0 = leave tabs to the terminal)")
+	cmd.PersistentFlags().StringVar(&flags.encoding, "encoding", "auto", "input encoding: auto, utf8, cp437, latin1 (auto keeps UTF-8 and guesses the rest)")
+	cmd.PersistentFlags().BoolVar(&flags.editTasks, "edit-tasks", false, "let Space check and uncheck task list items ({ and } select), saving the file")
+	cmd.PersistentFlags().BoolVar(&flags.diff, "diff", false, "compare two files, old then new: show the new one with removed lines marked - and added ones + (( and ) jump between changes)")
+	cmd.PersistentFlags().StringVar(&flags.section, "section", "", "show only the section under this heading (an anchor as --toc lists it, or the heading text), up to the next heading of the same or a higher level")
+	cmd.PersistentFlags().BoolVar(&flags.navDefs, "nav-defs", false, "list definition list terms and lines that are bold alone in the table of contents, like headings")
+	cmd.PersistentFlags().BoolVar(&flags.watch, "watch", false, "reload the file when it changes on disk")
+	cmd.PersistentFlags().BoolVar(&flags.follow, "follow", false, "stream in text appended to the file, like tail -f (implies --watch)")
+	cmd.PersistentFlags().BoolVar(&flags.handshake, "handshake", false, "play a dial-up modem handshake before streaming (any key skips)")
+	cmd.PersistentFlags().BoolVar(&flags.loop, "loop", false, "stream the document again, from the top, a few seconds after it ends (any key stops)")
+	cmd.PersistentFlags().IntVar(&flags.baudrate, "baudrate", 9600, "modem baud rate (bits/sec), e.g., 1200, 9600, 115200, 256000")
+	var monoStr, scrollStr, wrapModeStr, progressStr, colorStr, timeStr, barStyleStr, barCharsStr, frontMatterStr string
+	cmd.PersistentFlags().StringVar(&frontMatterStr, "front-matter", "show", "leading YAML (---) or TOML (+++) front matter: show (in the info panel, i), hide, raw (render it as Markdown)")
+	cmd.PersistentFlags().StringVar(&barStyleStr, "bar-style", "blocks", "progress bar glyphs: blocks, shades, ascii")
+	cmd.PersistentFlags().StringVar(&barCharsStr, "bar-chars", "", "progress bar fill and empty characters, e.g. \"#-\" (overrides --bar-style)")
+	cmd.PersistentFlags().StringVar(&timeStr, "time", "iso", "header file time: iso, relative (3 hours ago), none")
+	cmd.PersistentFlags().StringVar(&colorStr, "color", "auto", "color level: auto, 16, 256, truecolor (overrides detection)")
+	cmd.PersistentFlags().StringVar(&progressStr, "progress", "lines", "progress bar label: percent, lines, both")
+	cmd.PersistentFlags().StringVar(&wrapModeStr, "wrap-mode", "word", "how to break long lines: word, char (mid-word at the width), none (scroll sideways); w cycles")
+	cmd.PersistentFlags().Float64Var(&flags.autoscrollSpeed, "autoscroll-speed", 2, "auto-scroll (a) speed in lines per second")
+	cmd.PersistentFlags().IntVar(&flags.fps, "fps", defaultFPS, "animation frame rate; lower it (e.g. 15 or 30) to save bandwidth over SSH")
+	cmd.PersistentFlags().StringVar(&scrollStr, "scroll", "ease", "scroll animation: ease, linear, instant (no animation)")
+	cmd.PersistentFlags().StringVar(&monoStr, "mono", "off", "monochrome CRT mode: off, green, amber, white, cyan, blue")
+
+	// bench is hidden; a subcommand would otherwise bring cobra's help and
+	// completion commands into the usage
+	bench := benchCommand(&flags)
+	cmd.AddCommand(bench)
+	cmd.SetHelpCommand(&cobra.Command{Hidden: true})
+	cmd.CompletionOptions.DisableDefaultCmd = true
+	// errors are printed once, below, without the whole usage after them
+	cmd.SilenceUsage, cmd.SilenceErrors = true, true
+	cmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return fmt.Errorf("%w (see --help)", err)
+	})
+
+	// persistent, as the flags are, so bench takes them the same way
+	cmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
 		switch strings.ToLower(strings.TrimSpace(monoStr)) {
 		case "off", "":
 			flags.mono = monoOff
@@ -1036,15 +1924,169 @@ func main() {
 			flags.mono = monoAmber
 		case "white", "paperwhite":
 			flags.mono = monoWhite
+		case "cyan":
+			flags.mono = monoCyan
+		case "blue":
+			flags.mono = monoBlue
+		default:
+			return fmt.Errorf("invalid --mono value: %q (use off|green|amber|white|cyan|blue)", monoStr)
+		}
+		switch strings.ToLower(strings.TrimSpace(wrapModeStr)) {
+		case "word", "":
+			flags.wrapMode = wrapWord
+		case "char":
+			flags.wrapMode = wrapChar
+		case "none":
+			flags.wrapMode = wrapNone
+		default:
+			return fmt.Errorf("invalid --wrap-mode value: %q (use word|char|none)", wrapModeStr)
+		}
+		switch strings.ToLower(strings.TrimSpace(colorStr)) {
+		case "auto", "":
+			flags.color = colorAuto
+		case "16":
+			flags.color = color16
+		case "256":
+			flags.color = color256
+		case "truecolor", "24bit":
+			flags.color = colorTrue
+		default:
+			return fmt.Errorf("invalid --color value: %q (use auto|16|256|truecolor)", colorStr)
+		}
+		if quoteCharStr != "" {
+			r := []rune(quoteCharStr)
+			if len(r) != 1 || runewidth.RuneWidth(r[0]) != 1 {
+				return fmt.Errorf("invalid --quote-char %q: want one single-width character", quoteCharStr)
+			}
+			flags.quoteBar = r[0]
+		}
+		for _, c := range flags.quoteColors {
+			if termenv.TrueColor.Color(c) == nil {
+				return fmt.Errorf("invalid --quote-colors value: %q (use 0-255 or #rrggbb)", c)
+			}
+		}
+		if barCharsStr != "" {
+			chars, err := parseBarChars(barCharsStr)
+			if err != nil {
+				return err
+			}
+			flags.barChars = chars
+		} else if chars, ok := barStyles[strings.ToLower(strings.TrimSpace(barStyleStr))]; ok {
+			flags.barChars = chars
+		} else {
+			return fmt.Errorf("invalid --bar-style value: %q (use blocks|shades|ascii)", barStyleStr)
+		}
+		switch strings.ToLower(strings.TrimSpace(frontMatterStr)) {
+		case "show", "":
+			flags.frontMatter = frontMatterShow
+		case "hide":
+			flags.frontMatter = frontMatterHide
+		case "raw":
+			flags.frontMatter = frontMatterRaw
+		default:
+			return fmt.Errorf("invalid --front-matter value: %q (use show|hide|raw)", frontMatterStr)
+		}
+		switch strings.ToLower(strings.TrimSpace(timeStr)) {
+		case "iso", "":
+			flags.timeFormat = timeISO
+		case "relative":
+			flags.timeFormat = timeRelative
+		case "none":
+			flags.timeFormat = timeNone
 		default:
-			return fmt.Errorf("invalid --mono value: %q (use off|green|amber|white)", monoStr)
+			return fmt.Errorf("invalid --time value: %q (use relative|iso|none)", timeStr)
+		}
+		switch strings.ToLower(strings.TrimSpace(progressStr)) {
+		case "lines", "":
+			flags.progress = progressLines
+		case "percent":
+			flags.progress = progressPercent
+		case "both":
+			flags.progress = progressBoth
+		default:
+			return fmt.Errorf("invalid --progress value: %q (use percent|lines|both)", progressStr)
+		}
+		switch strings.ToLower(strings.TrimSpace(scrollStr)) {
+		case "ease", "":
+			flags.scroll = scrollEase
+		case "linear":
+			flags.scroll = scrollLinear
+		case "instant":
+			flags.scroll = scrollInstant
+		default:
+			return fmt.Errorf("invalid --scroll value: %q (use ease|linear|instant)", scrollStr)
+		}
+		if err := validateStyle("--style", flags.style); err != nil {
+			return err
+		}
+		if err := validateStyle("--style-dark", flags.styleDark); err != nil {
+			return err
+		}
+		if err := validateStyle("--style-light", flags.styleLight); err != nil {
+			return err
+		}
+		if err := validateCodeTheme(flags.codeTheme); err != nil {
+			return err
+		}
+		if flags.json && !flags.toc {
+			return errors.New("--json requires --toc")
+		}
+		if flags.cols < 0 {
+			return fmt.Errorf("invalid --cols: %d", flags.cols)
+		}
+		if flags.fps < 1 || flags.fps > 240 {
+			return fmt.Errorf("invalid --fps: %d (use 1-240)", flags.fps)
+		}
+		if flags.maxWidth < 0 {
+			return fmt.Errorf("invalid --max-width: %d", flags.maxWidth)
+		}
+		if flags.autoscrollSpeed <= 0 {
+			return fmt.Errorf("invalid --autoscroll-speed: %g", flags.autoscrollSpeed)
+		}
+		if flags.tabWidth < 0 {
+			return fmt.Errorf("invalid --tab-width: %d", flags.tabWidth)
+		}
+		if flags.renderTimeout < 0 {
+			return fmt.Errorf("invalid --render-timeout: %s (use 0 for no limit)", flags.renderTimeout)
+		}
+		if flags.degaussStrength < 1 || flags.degaussStrength > 3 {
+			return fmt.Errorf("invalid --degauss-strength: %d (use 1-3)", flags.degaussStrength)
+		}
+		if flags.diff && len(args) != 2 {
+			return errors.New("--diff needs two files: the old one, then the new one")
+		}
+		if flags.configPath != "" {
+			// a named config must be there and valid, whatever the mode
+			cfg, err := loadConfig(flags.configPath, true)
+			if err != nil {
+				return err
+			}
+			if _, err := cfg.keymap(); err != nil {
+				return fmt.Errorf("%s: %w", flags.configPath, err)
+			}
+		}
+		if flags.forceColor && flags.noColor {
+			return errors.New("--force-color and --no-color contradict each other")
+		}
+		if flags.strict && !flags.print && !flags.toc {
+			return errors.New("--strict needs --print or --toc")
 		}
 		if flags.baudrate < 0 {
 			return fmt.Errorf("invalid --baudrate: %d", flags.baudrate)
 		}
+		flags.encoding = strings.ToLower(strings.TrimSpace(flags.encoding))
+		if err := validateEncoding(flags.encoding); err != nil {
+			return err
+		}
 		return nil
 	}
 
+	// bench is the subcommand only as the first argument, and not when it
+	// is the file to show (mdnfo --print bench, mdnfo bench --mono green):
+	// a file named bench with no other file after it
+	if a := os.Args[1:]; len(a) == 0 || a[0] != "bench" || isFile("bench") && !slices.ContainsFunc(a[1:], isFile) {
+		cmd.RemoveCommand(bench)
+	}
 	if err := cmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, "error:", err)
 		os.Exit(1)
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/viewport"
+)
+
+// testModel is a model showing lines rendered lines in a viewport height
+// rows tall, with no document behind them.
+func testModel(lines, height int) model {
+	m := model{view: viewport.New(80, height), totalLines: lines, linkIndex: -1, taskIndex: -1, fps: 60}
+	m.view.SetContent(strings.TrimSuffix(strings.Repeat("x\n", lines), "\n"))
+	return m
+}
+
+// docModel is the model for raw as --print would set it up, rendering in
+// style with no terminal to ask.
+func docModel(raw, style string) model {
+	flags := startFlags{print: true, fps: 60, tabWidth: 4, maxWidth: 100}
+	return initialModel("test.md", raw, style, 0, time.Time{}, int64(len(raw)), flags)
+}
+
+func TestFollowLinkDuplicateAnchors(t *testing.T) {
+	tests := []struct {
+		target string
+		want   int // YOffset after following
+	}{
+		{"#guide", 0},
+		{"#setup", 10},
+		{"#setup-1", 20},
+		{"#setup-2", 30},
+		{"#Setup", 10}, // loose match: the first
+	}
+	for _, tt := range tests {
+		m := testModel(100, 10)
+		m.headings = parseHeadings(dupDoc, false)
+		for i := range m.headings {
+			m.headings[i].renderedLine = i * 10
+		}
+		m.followLink(link{text: "x", target: tt.target, renderedLine: -1})
+		if m.view.YOffset != tt.want {
+			t.Errorf("%s: YOffset = %d, want %d", tt.target, m.view.YOffset, tt.want)
+		}
+	}
+}
+
+func TestStartScrollTo(t *testing.T) {
+	tests := []struct {
+		name      string
+		easing    scrollEasing
+		target    int
+		wantOff   int // YOffset straight after the call
+		animating bool
+	}{
+		{"instant", scrollInstant, 40, 40, false},
+		{"instant clamped", scrollInstant, 500, 90, false},
+		{"instant up", scrollInstant, -3, 0, false},
+		{"ease", scrollEase, 40, 0, true},
+		{"linear", scrollLinear, 40, 0, true},
+		{"already there", scrollEase, 0, 0, false},
+	}
+	for _, tt := range tests {
+		m := testModel(100, 10)
+		m.easing = tt.easing
+		cmd := m.startScrollTo(tt.target)
+		if m.view.YOffset != tt.wantOff || m.animating != tt.animating {
+			t.Errorf("%s: YOffset %d, animating %v; want %d, %v", tt.name, m.view.YOffset, m.animating, tt.wantOff, tt.animating)
+		}
+		if !tt.animating && cmd != nil {
+			t.Errorf("%s: started a ticker with nothing to animate", tt.name)
+		}
+	}
+}
+
+func TestUntilNextMinute(t *testing.T) {
+	mod := time.Date(2024, 5, 1, 12, 0, 20, 0, time.UTC)
+	tests := []struct {
+		now  time.Time
+		want time.Duration
+	}{
+		{mod, time.Minute},
+		{mod.Add(15 * time.Second), 45 * time.Second},
+		{mod.Add(3*time.Minute + 59*time.Second), time.Second},
+		{mod.Add(-10 * time.Second), 10 * time.Second}, // dated in the future
+	}
+	for _, tt := range tests {
+		if got := untilNextMinute(mod, tt.now); got != tt.want {
+			t.Errorf("untilNextMinute(+%v) = %v, want %v", tt.now.Sub(mod), got, tt.want)
+		}
+	}
+}
+
+func TestClockTick(t *testing.T) {
+	for _, f := range []timeFormat{timeISO, timeRelative, timeNone} {
+		m := testModel(10, 5)
+		m.timeFormat = f
+		if got := m.clockTick() != nil; got != (f == timeRelative) {
+			t.Errorf("format %d: clock ticking is %v", f, got)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/term"
+)
+
+// ---------- openURL ----------
+
+// openTimeout is how long a launcher gets to fail. Openers like xdg-open
+// exit once the browser has the URL; a browser run directly may never
+// exit, and is left running after this.
+const openTimeout = 3 * time.Second
+
+// openFailedMsg reports a link launcher that couldn't start or exited
+// with an error.
+type openFailedMsg struct{ err error }
+
+// openURL launches browser, a command template where %s stands for the URL
+// (appended when absent), or the OS default opener when browser is empty.
+// The launcher runs in its own process group, away from the terminal's
+// signals, and is waited on in the background: the returned command
+// reports an openFailedMsg if it fails within openTimeout, else nothing.
+func openURL(u, browser string) tea.Cmd {
+	var cmd *exec.Cmd
+	if args := strings.Fields(browser); len(args) > 0 {
+		found := false
+		for i, a := range args {
+			if strings.Contains(a, "%s") {
+				args[i] = strings.ReplaceAll(a, "%s", u)
+				found = true
+			}
+		}
+		if !found {
+			args = append(args, u)
+		}
+		cmd = exec.Command(args[0], args[1:]...)
+	} else {
+		switch runtime.GOOS {
+		case "darwin":
+			cmd = exec.Command("open", u)
+		case "windows":
+			cmd = exec.Command("cmd", "/c", "start", u)
+		default:
+			cmd = exec.Command("xdg-open", u)
+		}
+	}
+	var stderr bytes.Buffer
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = nil, nil, &stderr
+	detach(cmd)
+	return func() tea.Msg {
+		if err := cmd.Start(); err != nil {
+			return openFailedMsg{err}
+		}
+		done := make(chan error, 1)
+		go func() { done <- cmd.Wait() }() // reaps it however long it runs
+		select {
+		case err := <-done:
+			if err == nil {
+				return nil
+			}
+			// launchers name themselves in what they print
+			if first, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n"); first != "" {
+				err = errors.New(first)
+			} else {
+				err = fmt.Errorf("%s: %w", filepath.Base(cmd.Path), err)
+			}
+			return openFailedMsg{err}
+		case <-time.After(openTimeout):
+			return nil
+		}
+	}
+}
+
+// terminalSize asks the terminal, then the COLUMNS and LINES variables,
+// then settles on 80x24, per dimension.
+func terminalSize() (w, h int) {
+	w, h = 80, 24
+	if tw, th, err := term.GetSize(int(os.Stdout.Fd())); err == nil && tw > 0 && th > 0 {
+		return tw, th
+	}
+	if n, err := strconv.Atoi(strings.TrimSpace(os.Getenv("COLUMNS"))); err == nil && n > 0 {
+		w = n
+	}
+	if n, err := strconv.Atoi(strings.TrimSpace(os.Getenv("LINES"))); err == nil && n > 0 {
+		h = n
+	}
+	return w, h
+}
+
+// browserCommand is --browser, else the first entry of $BROWSER (which
+// may list several commands separated by colons).
+func browserCommand(flag string) string {
+	if flag != "" {
+		return flag
+	}
+	env, _, _ := strings.Cut(os.Getenv("BROWSER"), ":")
+	return strings.TrimSpace(env)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ---------- --pager ----------
+
+// pagerCommand resolves --pager: a command line, or "auto" for $PAGER and
+// then less.
+func pagerCommand(flag string) string {
+	if flag != "auto" {
+		return flag
+	}
+	if p := strings.TrimSpace(os.Getenv("PAGER")); p != "" {
+		return p
+	}
+	return "less -R"
+}
+
+// page pipes out into the pager command line; when the pager can't be
+// found, out goes straight to stdout instead.
+func page(out, pager string) error {
+	args := strings.Fields(pager)
+	if len(args) == 0 {
+		_, err := fmt.Fprintln(os.Stdout, out)
+		return err
+	}
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		_, err := fmt.Fprintln(os.Stdout, out)
+		return err
+	}
+	cmd := exec.Command(path, args[1:]...)
+	cmd.Stdin = strings.NewReader(out + "\n")
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	cmd.Env = os.Environ()
+	if os.Getenv("LESS") == "" {
+		// a bare $PAGER=less would show the colors as escape codes
+		cmd.Env = append(cmd.Env, "LESS=R")
+	}
+	// how the pager exits (q, a signal) is its own business
+	var exitErr *exec.ExitError
+	if err := cmd.Run(); err != nil && !errors.As(err, &exitErr) {
+		return fmt.Errorf("pager %s: %w", args[0], err)
+	}
+	return nil
+}
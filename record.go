@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// ---------- asciinema recording (--rec) ----------
+
+// castHeader is the first line of an asciinema v2 cast.
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// recorder is the viewer's output: everything written goes to the
+// terminal and, stamped with the time since start, into a cast file. It
+// passes for the terminal itself (term.File) so Bubble Tea still sizes
+// and restores it.
+type recorder struct {
+	tty     *os.File
+	f       *os.File
+	w       *bufio.Writer
+	start   time.Time
+	cols    int
+	rows    int
+	pending []byte // a UTF-8 sequence split across writes
+	err     error  // first error writing the cast
+}
+
+// newRecorder creates the cast file at path and writes its header.
+func newRecorder(path string, tty *os.File, title string) (*recorder, error) {
+	cols, rows, err := term.GetSize(int(tty.Fd()))
+	if err != nil {
+		cols, rows = 80, 25
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &recorder{tty: tty, f: f, w: bufio.NewWriter(f), start: time.Now(), cols: cols, rows: rows}
+	hdr, _ := json.Marshal(castHeader{
+		Version:   2,
+		Width:     cols,
+		Height:    rows,
+		Timestamp: r.start.Unix(),
+		Title:     "mdnfo " + title,
+		Env:       map[string]string{"TERM": os.Getenv("TERM"), "SHELL": os.Getenv("SHELL")},
+	})
+	r.line(hdr)
+	return r, nil
+}
+
+func (r *recorder) Read(p []byte) (int, error) { return r.tty.Read(p) }
+func (r *recorder) Fd() uintptr                { return r.tty.Fd() }
+
+// Close is a no-op for Bubble Tea; the cast is finished by finish.
+func (r *recorder) Close() error { return nil }
+
+// Write sends p to the terminal and records it as an output event, after
+// a resize event if the terminal changed size since the last write.
+func (r *recorder) Write(p []byte) (int, error) {
+	n, err := r.tty.Write(p)
+	t := time.Since(r.start).Seconds()
+	if cols, rows, err := term.GetSize(int(r.tty.Fd())); err == nil && (cols != r.cols || rows != r.rows) {
+		r.cols, r.rows = cols, rows
+		r.event(t, "r", fmt.Sprintf("%dx%d", cols, rows))
+	}
+	data := append(r.pending, p[:n]...)
+	cut := utf8Complete(data)
+	r.pending = append([]byte(nil), data[cut:]...)
+	if cut > 0 {
+		r.event(t, "o", string(data[:cut]))
+	}
+	return n, err
+}
+
+// finish flushes the cast and closes it, reporting the first error met
+// while recording.
+func (r *recorder) finish() error {
+	if len(r.pending) > 0 {
+		r.event(time.Since(r.start).Seconds(), "o", string(r.pending))
+	}
+	if err := r.w.Flush(); err != nil && r.err == nil {
+		r.err = err
+	}
+	if err := r.f.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	if r.err != nil {
+		return fmt.Errorf("--rec %s: %w", r.f.Name(), r.err)
+	}
+	return nil
+}
+
+func (r *recorder) event(t float64, kind, data string) {
+	b, _ := json.Marshal([]any{float64(int64(t*1e6)) / 1e6, kind, data})
+	r.line(b)
+}
+
+func (r *recorder) line(b []byte) {
+	if r.err != nil {
+		return
+	}
+	if _, err := r.w.Write(append(b, '\n')); err != nil {
+		r.err = err
+	}
+}
+
+// utf8Complete is the length of b without a trailing, incomplete UTF-8
+// sequence, which JSON would otherwise mangle.
+func utf8Complete(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(b)
+}
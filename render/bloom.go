@@ -0,0 +1,93 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+)
+
+// monoRGB is each phosphor's 24-bit color.
+var monoRGB = map[Mono][3]float64{
+	MonoGreen: {0, 255, 128},
+	MonoAmber: {255, 176, 0},
+	MonoWhite: {230, 230, 230},
+	MonoCyan:  {0, 230, 255},
+	MonoBlue:  {80, 150, 255},
+}
+
+// bloomLevels scale the phosphor color from sparse glyphs (dimmest) to
+// dense ones, whose glow spills toward white.
+var bloomLevels = []float64{0.7, 0.85, 1.0, 1.15}
+
+// Bloom paints s in m's color like Monochrome, but in 24-bit color with
+// the intensity following glyph density: runs of heavy glyphs (blocks,
+// capitals) glow brighter, thin punctuation fades. The color only changes
+// between runs, so the output stays close to Monochrome's in size.
+func Bloom(s string, m Mono) string {
+	base, ok := monoRGB[m]
+	if !ok {
+		return s
+	}
+	lines := strings.Split(StripANSI(s), "\n")
+	var b strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		rs := []rune(line)
+		level := -1
+		for j, r := range rs {
+			if r != ' ' {
+				d := 2 * glyphWeight(r)
+				if j > 0 {
+					d += glyphWeight(rs[j-1])
+				}
+				if j+1 < len(rs) {
+					d += glyphWeight(rs[j+1])
+				}
+				if l := min(len(bloomLevels)-1, int(d/4*float64(len(bloomLevels)))); l != level {
+					level = l
+					b.WriteString(bloomSGR(base, bloomLevels[l]))
+				}
+			}
+			b.WriteRune(r)
+		}
+		if level >= 0 {
+			b.WriteString("\x1b[0m")
+		}
+	}
+	return b.String()
+}
+
+// glyphWeight is roughly how much of its cell a glyph lights, 0 to 1.
+func glyphWeight(r rune) float64 {
+	switch {
+	case r == ' ':
+		return 0
+	case r == '█' || r == '▓' || r == '■':
+		return 1
+	case r == '▒' || (r >= '▀' && r <= '▐'):
+		return 0.8
+	case r == '░':
+		return 0.5
+	case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '#', r == '@', r == '%', r == '&':
+		return 0.7
+	case strings.ContainsRune(".,:;'`-_\"", r):
+		return 0.2
+	default:
+		return 0.5
+	}
+}
+
+// bloomSGR is the truecolor foreground for base scaled by k; channels
+// that saturate push the rest up, toward white.
+func bloomSGR(base [3]float64, k float64) string {
+	var c [3]int
+	over := 0.0
+	for _, v := range base {
+		over = max(over, v*k-255)
+	}
+	for i, v := range base {
+		c[i] = int(min(255, v*k+over/2))
+	}
+	return fmt.Sprintf("\x1b[38;2;%d;%d;%dm", c[0], c[1], c[2])
+}
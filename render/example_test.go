@@ -0,0 +1,31 @@
+package render_test
+
+import (
+	"fmt"
+	"strings"
+
+	"mdnfo/render"
+)
+
+func ExampleRender() {
+	src := "# Hello\n\nRendered the *NFO* way, clipped to a classic canvas.\n"
+	out, err := render.Render(src, render.Options{
+		Width:     40,
+		Style:     "dark",
+		Mono:      render.MonoGreen,
+		Scanlines: true,
+		Clip:      80,
+	})
+	if err != nil {
+		panic(err)
+	}
+	// the text, without the green and the scanlines
+	for _, line := range strings.Split(strings.TrimSpace(render.StripANSI(out)), "\n") {
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+	// Output:
+	// Hello
+	//
+	//   Rendered the NFO way, clipped to a
+	//   classic canvas.
+}
@@ -0,0 +1,61 @@
+package render
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/glamour/ansi"
+)
+
+// ---------- bundled and added styles ----------
+
+// The presets are glamour JSON styles shipped inside the binary, named
+// after their file: green-crt and amber-crt pair with the mono modes.
+//
+//go:embed styles/*.json
+var presetFS embed.FS
+
+var (
+	extraStyles = map[string]ansi.StyleConfig{}
+	presetNames []string
+)
+
+func init() {
+	entries, err := presetFS.ReadDir("styles")
+	if err != nil {
+		panic(err)
+	}
+	for _, e := range entries {
+		b, err := presetFS.ReadFile("styles/" + e.Name())
+		if err != nil {
+			panic(err)
+		}
+		name := strings.TrimSuffix(e.Name(), ".json")
+		if err := AddStyle(name, b); err != nil {
+			panic(err) // a broken preset is a build mistake
+		}
+		presetNames = append(presetNames, name)
+	}
+}
+
+// AddStyle registers the glamour JSON style b under name, so Markdown and
+// ResolveStyle accept the name like a built-in one; mdnfo uses it for
+// styles fetched from a URL. Add styles before rendering with them.
+func AddStyle(name string, b []byte) error {
+	var cfg ansi.StyleConfig
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return fmt.Errorf("style %s: %w", name, err)
+	}
+	extraStyles[styleKey(name)] = cfg
+	return nil
+}
+
+// extraStyle looks style up among the presets and added styles.
+func extraStyle(style string) (ansi.StyleConfig, bool) {
+	cfg, ok := extraStyles[styleKey(style)]
+	return cfg, ok
+}
+
+func styleKey(style string) string { return strings.ToLower(strings.TrimSpace(style)) }
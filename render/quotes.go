@@ -0,0 +1,94 @@
+package render
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/muesli/termenv"
+)
+
+// ---------- blockquote bars ----------
+
+var reLeadSGR = regexp.MustCompile(`^\x1b\[[0-9;]*m`)
+
+// QuoteBars restyles the blockquote bars glamour draws at the start of
+// quoted lines, one per nesting level. bar, when not zero, replaces the
+// style's bar glyph; colors (256-palette numbers or #rrggbb), when given,
+// color the bars by depth, outermost first, cycling for deeper levels.
+// Styles whose quotes have no visible bar are left alone.
+func QuoteBars(s, style string, bar rune, colors []string) string {
+	if bar == 0 && len(colors) == 0 {
+		return s
+	}
+	cfg, err := ResolveStyle(style)
+	if err != nil || cfg.BlockQuote.IndentToken == nil {
+		return s
+	}
+	token := *cfg.BlockQuote.IndentToken
+	if strings.TrimSpace(token) == "" {
+		return s
+	}
+	glyph := token
+	if bar != 0 {
+		_, n := utf8.DecodeRuneInString(token)
+		glyph = string(bar) + token[n:]
+	}
+	var sgr []string
+	for _, c := range colors {
+		if col := termenv.TrueColor.Color(c); col != nil {
+			sgr = append(sgr, "\x1b["+col.Sequence(false)+"m")
+		}
+	}
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = quoteLine(line, token, glyph, sgr)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// quoteLine rewrites the run of bar tokens after line's margin, each of
+// which glamour wraps in its own color and reset.
+func quoteLine(line, token, glyph string, sgr []string) string {
+	// the margin: spaces, and the empty color spans glamour leaves around them
+	rest := line
+	for rest != "" {
+		if loc := reLeadSGR.FindStringIndex(rest); loc != nil {
+			if strings.HasPrefix(rest[loc[1]:], token) {
+				break // the first bar's own color
+			}
+			rest = rest[loc[1]:]
+		} else if rest[0] == ' ' {
+			rest = rest[1:]
+		} else {
+			break
+		}
+	}
+	var b strings.Builder
+	b.WriteString(line[:len(line)-len(rest)])
+	depth := 0
+	for {
+		open := reLeadSGR.FindString(rest)
+		after := rest[len(open):]
+		if !strings.HasPrefix(after, token) {
+			break
+		}
+		after = after[len(token):]
+		closing := ""
+		if strings.HasPrefix(after, "\x1b[0m") {
+			closing = "\x1b[0m"
+		}
+		if len(sgr) > 0 {
+			open, closing = sgr[depth%len(sgr)], "\x1b[0m"
+		}
+		b.WriteString(open + glyph + closing)
+		rest = strings.TrimPrefix(after, "\x1b[0m")
+		depth++
+	}
+	if depth == 0 {
+		return line
+	}
+	b.WriteString(rest)
+	return b.String()
+}
@@ -0,0 +1,365 @@
+// Package render is mdnfo's Markdown renderer: glamour output plus the
+// CRT-style post effects (monochrome phosphor, scanlines, a hard column
+// clip), usable without the terminal UI.
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/glamour/ansi"
+	"github.com/charmbracelet/glamour/styles"
+	"github.com/mattn/go-runewidth"
+	"github.com/muesli/termenv"
+	"golang.org/x/term"
+)
+
+// Options control Render. The zero value renders at 80 columns with the
+// auto style and no effects.
+type Options struct {
+	Width     int    // wrap width; 0 = 80
+	NoWrap    bool   // leave lines as long as they are; Width is ignored
+	Style     string // glamour style name or JSON style file; "" = auto
+	CodeTheme string // chroma theme for code blocks; "" = from Style
+	TabWidth  int    // expand tabs to stops this far apart; 0 = leave tabs
+
+	PreserveBreaks bool // keep paragraphs' line breaks (MarkdownLines)
+
+	QuoteBar    rune     // blockquote bar glyph; 0 = from Style
+	QuoteColors []string // blockquote bar colors by nesting depth; nil = from Style
+
+	Mono       Mono // recolor everything in one phosphor color
+	TrueColor  bool // use 24-bit color for Mono
+	Palette256 bool // use the 256-color palette for Mono
+	Bloom      bool // with Mono and TrueColor, glow by glyph density
+	Scanlines  bool // dim every other line
+	Inverse    bool // reverse video, like a terminal's reverse switch
+	Clip       int  // cut lines at this many columns (80 for a classic canvas); 0 = no clip
+	NoColor    bool // plain text: strip all escapes, ignore Mono, Scanlines and Inverse
+
+	// Overlay, when set, runs between the color effects and Scanlines,
+	// for marks that must keep their own colors under Mono (search hits,
+	// a selected link) and still be scanlined.
+	Overlay func(string) string
+}
+
+// Render renders raw Markdown and applies the effects in opts.
+func Render(raw string, opts Options) (string, error) {
+	width := opts.Width
+	if width <= 0 {
+		width = 80
+	}
+	if opts.NoWrap {
+		width = 0
+	}
+	if opts.TabWidth > 0 {
+		raw = ExpandTabs(raw, opts.TabWidth)
+	}
+	out, err := markdown(raw, width, opts.Style, opts.CodeTheme, opts.PreserveBreaks)
+	if err != nil {
+		return "", err
+	}
+	out = QuoteBars(out, opts.Style, opts.QuoteBar, opts.QuoteColors)
+	return Effects(out, opts), nil
+}
+
+// Effects applies opts' post effects to rendered output, in Render's
+// order: Mono (or Bloom), Overlay, Scanlines, Inverse, then Clip. The
+// glamour settings in opts are not used.
+func Effects(out string, opts Options) string {
+	if opts.NoColor {
+		out = StripANSI(out)
+	} else {
+		if opts.Bloom && opts.TrueColor {
+			out = Bloom(out, opts.Mono)
+		} else {
+			out = Monochrome(out, opts.Mono, opts.TrueColor, opts.Palette256)
+		}
+		if opts.Overlay != nil {
+			out = opts.Overlay(out)
+		}
+		if opts.Scanlines {
+			out = Scanlines(out)
+		}
+		if opts.Inverse {
+			out = Inverse(out)
+		}
+	}
+	if opts.Clip > 0 {
+		out = ClipColumns(out, opts.Clip)
+	}
+	return out
+}
+
+// ---------- glamour ----------
+
+// Markdown renders raw with glamour, wrapped at width. style is a glamour
+// style name, a preset or added style (AddStyle), "auto", or the path to a
+// JSON style file; codeTheme, when set, replaces only the style's code
+// block theme.
+func Markdown(raw string, width int, style, codeTheme string) (string, error) {
+	return markdown(raw, width, style, codeTheme, false)
+}
+
+// MarkdownLines is Markdown keeping the line breaks inside paragraphs
+// where the source has them, for poetry, addresses and other text laid
+// out by line. Glamour otherwise joins a paragraph's lines, hard breaks
+// (two trailing spaces or a backslash) included.
+func MarkdownLines(raw string, width int, style, codeTheme string) (string, error) {
+	return markdown(raw, width, style, codeTheme, true)
+}
+
+func markdown(raw string, width int, style, codeTheme string, keepBreaks bool) (string, error) {
+	opts := []glamour.TermRendererOption{
+		glamour.WithWordWrap(width),
+	}
+	if keepBreaks {
+		opts = append(opts, glamour.WithPreservedNewLines())
+	}
+
+	if codeTheme != "" {
+		// resolve the full style so only the code block theme changes
+		cfg, err := ResolveStyle(style)
+		if err != nil {
+			return "", err
+		}
+		cfg.CodeBlock.Theme = codeTheme
+		opts = append(opts, glamour.WithStyles(cfg))
+		return renderWith(raw, opts)
+	}
+
+	name := strings.ToLower(strings.TrimSpace(style))
+	extra, isExtra := extraStyle(name)
+	switch {
+	case name == "" || name == "auto":
+		opts = append(opts, glamour.WithAutoStyle())
+	case styles.DefaultStyles[name] != nil:
+		opts = append(opts, glamour.WithStylePath(name))
+	case isExtra:
+		opts = append(opts, glamour.WithStyles(extra))
+	default:
+		// If it's a file path to a JSON style, use it; else fall back to auto.
+		if _, err := os.Stat(style); err == nil {
+			opts = append(opts, glamour.WithStylesFromJSONFile(style))
+		} else {
+			opts = append(opts, glamour.WithAutoStyle())
+		}
+	}
+
+	return renderWith(raw, opts)
+}
+
+func renderWith(raw string, opts []glamour.TermRendererOption) (string, error) {
+	r, err := glamour.NewTermRenderer(opts...)
+	if err != nil {
+		return "", err
+	}
+	return r.Render(raw)
+}
+
+// ResolveStyle returns a copy of the glamour style config that style
+// names, following the same rules as Markdown.
+func ResolveStyle(style string) (ansi.StyleConfig, error) {
+	name := strings.ToLower(strings.TrimSpace(style))
+	switch name {
+	case "", "auto":
+		if !term.IsTerminal(int(os.Stdout.Fd())) {
+			return styles.NoTTYStyleConfig, nil
+		}
+		if termenv.HasDarkBackground() {
+			return styles.DarkStyleConfig, nil
+		}
+		return styles.LightStyleConfig, nil
+	}
+	if cfg, ok := styles.DefaultStyles[name]; ok {
+		return *cfg, nil
+	}
+	if cfg, ok := extraStyle(name); ok {
+		return cfg, nil
+	}
+	if b, err := os.ReadFile(style); err == nil {
+		var cfg ansi.StyleConfig
+		if err := json.Unmarshal(b, &cfg); err != nil {
+			return ansi.StyleConfig{}, fmt.Errorf("style %s: %w", style, err)
+		}
+		return cfg, nil
+	}
+	return ResolveStyle("auto")
+}
+
+// StyleNames lists the style names Markdown accepts besides a JSON file
+// path: glamour's registry and the bundled presets.
+func StyleNames() []string {
+	names := make([]string, 0, len(styles.DefaultStyles)+len(presetNames)+1)
+	for n := range styles.DefaultStyles {
+		names = append(names, n)
+	}
+	names = append(names, presetNames...)
+	sort.Strings(names)
+	return append([]string{"auto"}, names...)
+}
+
+// ExpandTabs replaces each tab with spaces up to the next multiple of
+// width columns, so alignment doesn't depend on the terminal's tab stops.
+func ExpandTabs(s string, width int) string {
+	if width <= 0 || !strings.Contains(s, "\t") {
+		return s
+	}
+	var b strings.Builder
+	col := 0
+	for _, r := range s {
+		switch r {
+		case '\t':
+			n := width - col%width
+			b.WriteString(strings.Repeat(" ", n))
+			col += n
+		case '\n':
+			b.WriteRune(r)
+			col = 0
+		default:
+			b.WriteRune(r)
+			col += runewidth.RuneWidth(r)
+		}
+	}
+	return b.String()
+}
+
+// ---------- effects ----------
+
+// ANSI matches the zero-width escapes in rendered output: CSI sequences
+// (SGR among them, private parameters like ?25l and colon-separated colors
+// like 38:2::1:2:3 included), OSC strings whole, BEL or ST ended (OSC 8
+// hyperlinks with or without parameters, iTerm2 OSC 1337 images, titles in
+// raw files), and the other inline image escapes (kitty APC _G, SIXEL DCS
+// inside a cursor save/restore). Each match is one token to streaming,
+// never cut in two.
+var ANSI = regexp.MustCompile(`\x1b\[[0-9;:?<=>]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b_G[^\x1b]*\x1b\\|\x1b7\x1bP[^\x1b]*\x1b\\\x1b8`)
+
+// StripANSI removes every escape ANSI matches.
+func StripANSI(s string) string { return ANSI.ReplaceAllString(s, "") }
+
+// Mono is a monochrome CRT phosphor color.
+type Mono int
+
+const (
+	MonoOff Mono = iota
+	MonoGreen
+	MonoAmber
+	MonoWhite
+	MonoCyan
+	MonoBlue
+)
+
+func (m Mono) String() string {
+	switch m {
+	case MonoGreen:
+		return "Green"
+	case MonoAmber:
+		return "Amber"
+	case MonoWhite:
+		return "Paperwhite"
+	case MonoCyan:
+		return "Cyan"
+	case MonoBlue:
+		return "Blue"
+	default:
+		return "Off"
+	}
+}
+
+// MonoSGR returns the escapes that switch to and from m's color, in
+// 24-bit color when truecolor is set, else the 256 palette, else 16 colors.
+func MonoSGR(m Mono, truecolor, palette256 bool) (open, close string) {
+	var fg string
+	switch m {
+	case MonoGreen:
+		fg = "32"
+	case MonoAmber:
+		fg = "33"
+	case MonoWhite:
+		fg = "37"
+	case MonoCyan:
+		fg = "36"
+	case MonoBlue:
+		fg = "94" // plain blue is too dark to read on black
+	default:
+		return "", ""
+	}
+	if palette256 {
+		switch m {
+		case MonoGreen:
+			fg = "38;5;82"
+		case MonoAmber:
+			fg = "38;5;214"
+		case MonoWhite:
+			fg = "38;5;252"
+		case MonoCyan:
+			fg = "38;5;51"
+		case MonoBlue:
+			fg = "38;5;75"
+		}
+	}
+	if truecolor {
+		c := monoRGB[m]
+		fg = fmt.Sprintf("38;2;%d;%d;%d", int(c[0]), int(c[1]), int(c[2]))
+	}
+	return "\x1b[" + fg + "m", "\x1b[0m"
+}
+
+// Monochrome strips s of its colors and paints it all in m's color.
+// MonoOff returns s unchanged.
+func Monochrome(s string, m Mono, truecolor, palette256 bool) string {
+	if m == MonoOff {
+		return s
+	}
+	open, close := MonoSGR(m, truecolor, palette256)
+	return open + StripANSI(s) + close
+}
+
+// Scanlines dims every other line of s.
+func Scanlines(s string) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	for i := 1; i < len(lines); i += 2 {
+		lines[i] = "\x1b[2m" + lines[i] + "\x1b[22m"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// reverseVideo flips the reverse video inside a line: resets keep it on,
+// and spans already inverted, like a search highlight, turn it off.
+var reverseVideo = strings.NewReplacer("\x1b[0m", "\x1b[0;7m", "\x1b[m", "\x1b[0;7m", "\x1b[7m", "\x1b[27m", "\x1b[27m", "\x1b[7m")
+
+// Inverse shows s in reverse video, light on dark turned dark on light:
+// every line is inverted and padded to the widest, so the text sits in a
+// solid block.
+func Inverse(s string) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	widths := make([]int, len(lines))
+	widest := 0
+	for i, l := range lines {
+		widths[i] = runewidth.StringWidth(StripANSI(l))
+		widest = max(widest, widths[i])
+	}
+	for i, l := range lines {
+		lines[i] = "\x1b[7m" + reverseVideo.Replace(l) + strings.Repeat(" ", widest-widths[i]) + "\x1b[27m"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ClipColumns cuts every line of s at cols columns. A line that has to be
+// cut loses its escapes.
+func ClipColumns(s string, cols int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	for i, l := range lines {
+		plain := StripANSI(l)
+		if runewidth.StringWidth(plain) > cols {
+			lines[i] = runewidth.Truncate(plain, cols, "")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,123 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStripANSI(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"plain", "hello", "hello"},
+		{"sgr", "\x1b[1;38;5;212mbold\x1b[0m text", "bold text"},
+		{"sgr reset", "\x1b[mtext\x1b[m", "text"},
+		{"sgr colon", "\x1b[38:2::255:0:128mrgb\x1b[0m", "rgb"},
+		{"private csi", "\x1b[?25lhidden cursor\x1b[?25h", "hidden cursor"},
+		{"csi intermediate", "a\x1b[2 qb", "ab"},
+		{"osc 8 bel", "\x1b]8;;https://example.com\x07link\x1b]8;;\x07", "link"},
+		{"osc 8 st", "\x1b]8;;https://example.com\x1b\\link\x1b]8;;\x1b\\", "link"},
+		{"osc 8 params", "\x1b]8;id=42;https://example.com\x1b\\link\x1b]8;;\x1b\\", "link"},
+		{"osc title", "\x1b]0;title\x07text", "text"},
+		{"kitty apc", "a\x1b_Gf=100,a=T;iVBORw0KGgo=\x1b\\b", "ab"},
+		{"sixel", "a\x1b7\x1bPq#0;2;0;0;0#0~~@@vv\x1b\\\x1b8b", "ab"},
+		{"mixed", "\x1b[31m\x1b]8;;u\x07红色\x1b]8;;\x07\x1b[0m", "红色"},
+	}
+	for _, tt := range tests {
+		got := StripANSI(tt.in)
+		if got != tt.want {
+			t.Errorf("%s: StripANSI(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+		if strings.ContainsRune(got, '\x1b') {
+			t.Errorf("%s: escape left in %q", tt.name, got)
+		}
+	}
+}
+
+// TestANSITokens checks each escape is matched whole, as streaming needs:
+// one token per sequence, nothing of it left in the text between.
+func TestANSITokens(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string // the escapes, in order
+	}{
+		{"sgr", "\x1b[1mA\x1b[0m", []string{"\x1b[1m", "\x1b[0m"}},
+		{"private csi", "\x1b[?25lA", []string{"\x1b[?25l"}},
+		{"sgr colon", "\x1b[38:2::1:2:3mA", []string{"\x1b[38:2::1:2:3m"}},
+		{"osc 8 bel", "\x1b]8;;https://x.y/a?b=c\x07A\x1b]8;;\x07", []string{"\x1b]8;;https://x.y/a?b=c\x07", "\x1b]8;;\x07"}},
+		{"osc 8 st", "\x1b]8;;https://x.y\x1b\\A\x1b]8;;\x1b\\", []string{"\x1b]8;;https://x.y\x1b\\", "\x1b]8;;\x1b\\"}},
+		{"kitty apc", "\x1b_Ga=T,f=100;AAAA\x1b\\A", []string{"\x1b_Ga=T,f=100;AAAA\x1b\\"}},
+		{"sixel", "\x1b7\x1bPq#0~-\x1b\\\x1b8A", []string{"\x1b7\x1bPq#0~-\x1b\\\x1b8"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, span := range ANSI.FindAllStringIndex(tt.in, -1) {
+			got = append(got, tt.in[span[0]:span[1]])
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("%s: tokens %q, want %q", tt.name, got, tt.want)
+		}
+		if rest := ANSI.ReplaceAllString(tt.in, ""); rest != "A" {
+			t.Errorf("%s: text between tokens is %q, want %q", tt.name, rest, "A")
+		}
+	}
+}
+
+// TestStripANSIGlamour feeds real glamour output through StripANSI.
+func TestStripANSIGlamour(t *testing.T) {
+	src := "# Title\n\nSome **bold** and `code` and a [link](https://example.com).\n\n" +
+		"> quoted\n\n- one\n- two\n\n```go\nfunc main() {}\n```\n"
+	for _, style := range []string{"dark", "light", "dracula"} {
+		out, err := Markdown(src, 60, style, "")
+		if err != nil {
+			t.Fatalf("%s: %v", style, err)
+		}
+		if !strings.Contains(out, "\x1b[") {
+			t.Fatalf("%s: no escapes in the output to strip", style)
+		}
+		plain := StripANSI(out)
+		if strings.ContainsRune(plain, '\x1b') {
+			t.Errorf("%s: escape left after StripANSI: %q", style, plain)
+		}
+		for _, want := range []string{"Title", "bold", "code", "quoted", "func main() {}"} {
+			if !strings.Contains(plain, want) {
+				t.Errorf("%s: %q missing from %q", style, want, plain)
+			}
+		}
+	}
+}
+
+// poem has one stanza of four lines: a hard break of two spaces, one of a
+// backslash, and a plain newline.
+const poem = "# Poem\n\nRoses are red,  \nviolets are blue,\\\nsugar is sweet\nand so are you.\n"
+
+func TestPreserveBreaks(t *testing.T) {
+	lines := func(out string) []string {
+		var ls []string
+		for _, l := range strings.Split(StripANSI(out), "\n") {
+			if l = strings.TrimSpace(l); l != "" && l != "# Poem" && l != "Poem" {
+				ls = append(ls, l)
+			}
+		}
+		return ls
+	}
+	for _, style := range []string{"dark", "notty"} {
+		out, err := Render(poem, Options{Width: 80, Style: style, PreserveBreaks: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"Roses are red,", "violets are blue,", "sugar is sweet", "and so are you."}
+		if got := lines(out); strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Errorf("%s: preserved lines %q, want %q", style, got, want)
+		}
+
+		out, err = Render(poem, Options{Width: 80, Style: style})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := lines(out); len(got) >= len(want) {
+			t.Errorf("%s: without PreserveBreaks the stanza kept %d lines: %q", style, len(got), got)
+		}
+	}
+}
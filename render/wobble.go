@@ -0,0 +1,31 @@
+package render
+
+import (
+	"fmt"
+	"math"
+)
+
+// Wobble repaints line in m's phosphor color (green when m is MonoOff)
+// with its hue pushed round by shift, -1 to 1: the color smear of a CRT
+// being degaussed. Without truecolor the line just takes the mono color.
+func Wobble(line string, m Mono, shift float64, truecolor, palette256 bool) string {
+	if m == MonoOff {
+		m = MonoGreen
+	}
+	if !truecolor {
+		open, close := MonoSGR(m, false, palette256)
+		return open + StripANSI(line) + close
+	}
+	c := monoRGB[m]
+	// rotate around the grey axis; a full turn at |shift| = 1
+	a := shift * 2 * math.Pi
+	cos, sin := math.Cos(a), math.Sin(a)
+	k := (1 - cos) / 3
+	s := math.Sqrt(1.0/3) * sin
+	var out [3]int
+	for i := range c {
+		v := c[i]*(cos+k) + c[(i+1)%3]*(k-s) + c[(i+2)%3]*(k+s)
+		out[i] = int(math.Max(0, math.Min(255, v)))
+	}
+	return fmt.Sprintf("\x1b[38;2;%d;%d;%dm", out[0], out[1], out[2]) + StripANSI(line) + "\x1b[0m"
+}
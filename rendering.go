@@ -0,0 +1,792 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	chromastyles "github.com/alecthomas/chroma/v2/styles"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mattn/go-runewidth"
+
+	"mdnfo/render"
+)
+
+// ---------- rendering ----------
+
+// printStyles writes the --list-styles output.
+func printStyles(w io.Writer) error {
+	for _, n := range render.StyleNames() {
+		if _, err := fmt.Fprintln(w, n); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, "\n--style also accepts the path or http(s) URL of a glamour JSON style file.")
+	return err
+}
+
+// styleCycle is the built-in styles T steps through, after the style the
+// viewer started with.
+var styleCycle = []string{"dark", "light", "dracula", "pink", "tokyo-night", "green-crt", "amber-crt"}
+
+// cycleStyle re-renders in the next style of the cycle, flashing its name.
+func (m *model) cycleStyle() {
+	if m.styles == nil {
+		m.styles = []string{m.theme}
+		for _, s := range styleCycle {
+			if !strings.EqualFold(s, strings.TrimSpace(m.theme)) {
+				m.styles = append(m.styles, s)
+			}
+		}
+	}
+	m.styleIndex = (m.styleIndex + 1) % len(m.styles)
+	m.theme = m.styles[m.styleIndex]
+	m.rewrap()
+	m.flash("style: " + m.theme)
+}
+
+// pickStyle settles --style auto for the viewer by asking the terminal for
+// its background color (OSC 11): --style-dark or --style-light. When the
+// terminal doesn't answer, glamour's own guess stands.
+func pickStyle(flags startFlags) string {
+	if name := strings.ToLower(strings.TrimSpace(flags.style)); name != "" && name != "auto" {
+		return flags.style
+	}
+	dark, ok := queryBackground(probeTimeout)
+	switch {
+	case !ok:
+		return flags.style
+	case dark:
+		return flags.styleDark
+	}
+	return flags.styleLight
+}
+
+// colorStyle is pickStyle for --force-color. Glamour's auto style goes
+// plain when stdout is not a terminal, so an undetected background falls
+// back to --style-dark.
+func colorStyle(flags startFlags) string {
+	style := pickStyle(flags)
+	if name := strings.ToLower(strings.TrimSpace(style)); name == "" || name == "auto" {
+		return flags.styleDark
+	}
+	return style
+}
+
+// validateStyle checks that style, given to flag, is a glamour style name
+// or a readable, valid JSON style file, so a typo or a broken file stops
+// mdnfo at startup instead of quietly rendering with the auto style. A URL
+// is fetched here, once, and kept for the renderer under that name.
+func validateStyle(flag, style string) error {
+	if isURL(style) {
+		b, err := fetchStyle(style)
+		if err != nil {
+			return fmt.Errorf("%s: %w", flag, err)
+		}
+		if err := render.AddStyle(style, b); err != nil {
+			return fmt.Errorf("invalid %s: %w", flag, err)
+		}
+		return nil
+	}
+	name := strings.ToLower(strings.TrimSpace(style))
+	for _, n := range render.StyleNames() {
+		if n == name {
+			return nil
+		}
+	}
+	if _, err := os.Stat(style); err != nil {
+		return fmt.Errorf("unknown %s %q: not a style name (see --list-styles) or a readable file", flag, style)
+	}
+	if _, err := render.ResolveStyle(style); err != nil {
+		return fmt.Errorf("invalid %s: %w", flag, err)
+	}
+	return nil
+}
+
+// validateCodeTheme checks name against chroma's style registry.
+func validateCodeTheme(name string) error {
+	if name == "" {
+		return nil
+	}
+	for _, n := range chromastyles.Names() {
+		if n == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown --code-theme %q; valid themes: %s", name, strings.Join(chromastyles.Names(), ", "))
+}
+
+func (m *model) recalcRendered(width, height int) {
+	// Fixed 80x25 mode keeps a classic canvas; --cols only fixes the width
+	if m.fixed8025 {
+		width = 80
+		height = 25
+	} else if m.cols > 0 {
+		width = m.cols
+	}
+	bodyHeight := height - m.chromeRows()
+	if bodyHeight < 1 {
+		bodyHeight = 1
+	}
+	m.view.YPosition = m.chromeRows() / 2
+	out, err := m.renderDocument(width - m.gutter)
+	if err == nil && m.lineNumbers {
+		// size the gutter for the full document, not the streamed part;
+		// re-render in the rare case the digit count changed
+		if g := gutterWidth(strings.Count(out, "\n") + 1); g != m.gutter {
+			m.gutter = g
+			out, err = m.renderDocument(width - m.gutter)
+		}
+	} else {
+		m.gutter = 0
+	}
+	if err != nil {
+		m.err = err
+		return
+	}
+	m.err = nil
+	m.renderedFull = out
+	if m.renderTimedOut {
+		m.renderTimedOut = false
+		m.flash(m.renderTimeoutNote())
+	}
+
+	// a --wrap wider than the screen only shows by scrolling sideways;
+	// say so once each time it starts to overflow
+	if over := m.effectiveWrap(width-m.gutter) > width-m.gutter; over && !m.wrapWarned {
+		m.flash(fmt.Sprintf("--wrap %d is wider than the screen (%d): Shift+→ scrolls, --clamp-wrap fits", m.wrapWidth, width-m.gutter))
+		m.wrapWarned = true
+	} else if !over {
+		m.wrapWarned = false
+	}
+
+	// Prepare the transmission tokens for modem emulation
+	m.prepareStreamTokens()
+
+	if m.view.Width != width || m.view.Height != bodyHeight {
+		m.view.Width = width
+		m.view.Height = bodyHeight
+	}
+	// Build view from current tx progress
+	m.refreshContent()
+	m.buildIndexes()
+}
+
+// chromeRows is how many rows the header and footer take: none in
+// minimal mode.
+func (m *model) chromeRows() int {
+	if m.minimal {
+		return 0
+	}
+	return 2
+}
+
+// screenHeight is the terminal height the current layout was made for.
+func (m *model) screenHeight() int { return m.view.Height + m.chromeRows() }
+
+const panStep = 8 // columns per pan key press
+
+const (
+	minWrapWidth  = 20
+	wrapStep      = 4
+	wrapBadgeTime = 2 * time.Second
+)
+
+// wrapMode is how long lines are broken, --wrap-mode.
+type wrapMode int
+
+const (
+	wrapWord wrapMode = iota // at word boundaries (glamour's own wrapping)
+	wrapChar                 // at exactly the wrap width, mid-word
+	wrapNone                 // not at all; scroll sideways instead
+)
+
+func (w wrapMode) String() string {
+	switch w {
+	case wrapChar:
+		return "char"
+	case wrapNone:
+		return "none"
+	default:
+		return "word"
+	}
+}
+
+// adjustWrap moves the wrap width by delta columns, clamped between
+// minWrapWidth and the canvas width. Reaching the canvas width switches
+// back to auto so the text keeps following terminal resizes.
+func (m *model) adjustWrap(delta int) {
+	width := m.view.Width - m.gutter
+	wrap := clamp(m.effectiveWrap(width)+delta, min(minWrapWidth, width), width)
+	if wrap == width && (m.maxWidth <= 0 || m.maxWidth >= width) {
+		wrap = 0
+	}
+	m.wrapBadgeFrames = m.frames(wrapBadgeTime)
+	if wrap == m.wrapWidth {
+		return
+	}
+	m.wrapWidth = wrap
+	m.rewrap()
+}
+
+// rewrap re-renders after a change to the text width or style, keeping
+// the reader at the same relative position in the document.
+func (m *model) rewrap() {
+	ratio := 0.0
+	if den := m.totalLines - m.view.Height; den > 0 {
+		ratio = float64(m.view.YOffset) / float64(den)
+	}
+	m.recalcRendered(m.view.Width, m.screenHeight())
+	m.view.SetYOffset(int(ratio * float64(max(0, m.totalLines-m.view.Height))))
+}
+
+// gutterWidth fits the largest line number plus a separating space.
+func gutterWidth(lines int) int {
+	return len(strconv.Itoa(max(1, lines))) + 1
+}
+
+// withGutter prefixes each line with its dimmed line number.
+func (m *model) withGutter(lines []string) string {
+	var b strings.Builder
+	for i, l := range lines {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		num := fmt.Sprintf("%*d ", m.gutter-1, i+1)
+		if !m.noColor {
+			num = "\x1b[2m" + num + "\x1b[22m"
+		}
+		b.WriteString(num)
+		b.WriteString(l)
+	}
+	return b.String()
+}
+
+// effectiveWrap is the glamour wrap width for a canvas of the given width.
+func (m *model) effectiveWrap(width int) int {
+	if m.wrapWidth > 0 {
+		if m.clampWrap && width > 0 {
+			return min(m.wrapWidth, width)
+		}
+		return m.wrapWidth
+	}
+	if m.fixed8025 {
+		return 80
+	}
+	if m.maxWidth > 0 && width > m.maxWidth {
+		return m.maxWidth
+	}
+	return width
+}
+
+// margin is the left padding that centers text held to --max-width on a
+// wider canvas. An explicit --wrap stays left-aligned.
+func (m *model) margin() int {
+	if m.wrapWidth > 0 || m.fixed8025 || m.maxWidth <= 0 || m.rawMode() || m.wrapMode == wrapNone {
+		return 0
+	}
+	return max(0, (m.view.Width-m.gutter-m.maxWidth)/2)
+}
+
+// renderPlain renders the whole document once with post effects applied,
+// for output outside the TUI.
+func (m *model) renderPlain(width int) (string, error) {
+	if m.cols > 0 {
+		width = m.cols
+	}
+	out, err := m.renderDocument(width)
+	if err != nil {
+		return "", err
+	}
+	opts := m.effectOptions()
+	if m.fixed8025 {
+		opts.Clip = 80
+	}
+	return render.Effects(out, opts), nil
+}
+
+// renderKey is everything besides the document text that shapes
+// renderFresh's output; post effects (scanlines, mono, BBS) are not part
+// of it.
+type renderKey struct {
+	wrap           int
+	wrapMode       wrapMode
+	theme          string
+	codeTheme      string
+	raw            bool
+	fixed8025      bool
+	osc8           bool
+	graphics       graphicsProto
+	preserveBreaks bool
+	quoteBar       rune
+	quoteColors    string // joined, to keep the key comparable
+	tabWidth       int
+	frontMatter    frontMatterMode
+	section        string
+	diffName       string
+}
+
+// renderCache holds the last render so toggling post effects doesn't
+// re-run glamour. Cleared whenever the document text changes.
+type renderCache struct {
+	key      renderKey
+	out      string
+	valid    bool
+	timedOut bool // out is the unrendered fallback, until renderDoneMsg
+}
+
+// renderKey is the cache key for a render at width columns.
+func (m *model) renderKey(width int) renderKey {
+	return renderKey{
+		wrap:           m.effectiveWrap(width),
+		wrapMode:       m.wrapMode,
+		theme:          m.theme,
+		codeTheme:      m.codeTheme,
+		raw:            m.rawMode(),
+		fixed8025:      m.fixed8025,
+		osc8:           m.osc8,
+		graphics:       m.graphics,
+		preserveBreaks: m.preserveBreaks,
+		quoteBar:       m.quoteBar,
+		quoteColors:    strings.Join(m.quoteColors, ","),
+		tabWidth:       m.tabWidth,
+		frontMatter:    m.frontMatter,
+		section:        m.section,
+		diffName:       m.diffName,
+	}
+}
+
+// renderDocument renders the document for width columns, reusing the
+// previous render when nothing that affects it has changed.
+func (m *model) renderDocument(width int) (string, error) {
+	key := m.renderKey(width)
+	if m.cache.valid && m.cache.key == key {
+		return m.cache.out, nil
+	}
+	m.lateRender = nil
+	out, err := m.renderFresh(width)
+	if err != nil {
+		return "", err
+	}
+	// the unrendered fallback is kept too, so a stuck glamour isn't waited
+	// on again for every key press; the late render replaces it
+	m.cache = renderCache{key: key, out: out, valid: true, timedOut: m.renderTimedOut}
+	if m.lateRender != nil {
+		m.renderWait = waitRender(key, m.markdown(), m.lateRender)
+		m.lateRender = nil
+	}
+	return out, nil
+}
+
+// renderDoneMsg is a render that timed out, finished at last.
+type renderDoneMsg struct {
+	key renderKey
+	raw string // the text it rendered
+	renderResult
+}
+
+// waitRender waits for the render that timed out on raw at key.
+func waitRender(key renderKey, raw string, late <-chan renderResult) tea.Cmd {
+	return func() tea.Msg {
+		return renderDoneMsg{key: key, raw: raw, renderResult: <-late}
+	}
+}
+
+// finishRender takes the late render in place of the fallback on screen.
+// A render of text or settings since changed is dropped, and the current
+// ones rendered now that glamour is free again.
+func (m *model) finishRender(msg renderDoneMsg) {
+	if !m.cache.valid || !m.cache.timedOut {
+		return // nothing is waiting on it
+	}
+	if msg.err == nil && msg.key == m.cache.key && msg.raw == m.markdown() {
+		m.cache = renderCache{key: msg.key, out: msg.out, valid: true}
+	} else {
+		m.cache.valid = false
+	}
+	off := m.view.YOffset
+	m.recalcRendered(m.view.Width, m.screenHeight())
+	m.view.SetYOffset(clamp(off, 0, max(0, m.totalLines-m.view.Height)))
+}
+
+// renderFresh runs the full render pipeline for a canvas of the given
+// width: image (and, in 80x25, wide table) extraction, glamour, then
+// injection.
+func (m *model) renderFresh(width int) (string, error) {
+	if m.diffName != "" {
+		return m.renderDiff(width)
+	}
+	raw := render.ExpandTabs(m.markdown(), m.tabWidth)
+	if m.rawMode() {
+		// verbatim: no glamour, no wrapping; wide art scrolls sideways
+		return strings.ReplaceAll(raw, "\r\n", "\n"), nil
+	}
+	wrap := m.effectiveWrap(width)
+	baseDir := "."
+	if m.localFile() {
+		baseDir = filepath.Dir(m.filename)
+	}
+	src, imgs := extractImages(raw, baseDir, m.graphics)
+	var tables []wideTable
+	if m.fixed8025 && m.wrapMode == wrapWord {
+		src, tables = extractTables(src, wrap)
+	}
+	glamourWrap := wrap
+	if m.wrapMode != wrapWord {
+		glamourWrap = 0 // unwrapped; char mode cuts the lines below
+	}
+	opts := render.Options{
+		Width:          glamourWrap,
+		NoWrap:         glamourWrap == 0,
+		Style:          m.theme,
+		CodeTheme:      m.codeTheme,
+		PreserveBreaks: m.preserveBreaks,
+		QuoteBar:       m.quoteBar,
+		QuoteColors:    m.quoteColors,
+	}
+	// everything the render needs is copied in: one that times out
+	// finishes in the background, after the model has moved on
+	md, wrapMode, graphics, osc8 := m.markdown(), m.wrapMode, m.graphics, m.osc8
+	out, late, err := withDeadline(m.renderTimeout, func() (string, error) {
+		out, err := render.Render(src, opts)
+		if err != nil {
+			return "", err
+		}
+		for i := range tables {
+			t := opts
+			t.Width, t.NoWrap = tables[i].width, false
+			if tables[i].rendered, err = render.Render(tables[i].src, t); err != nil {
+				return "", err
+			}
+		}
+		out = injectTables(out, tables)
+		if wrapMode == wrapChar {
+			out = charWrap(out, wrap)
+		}
+		out = injectImages(out, imgs, graphics, wrap)
+		if osc8 {
+			out = emitOSC8(md, out)
+		}
+		return out, nil
+	})
+	if errors.Is(err, errRenderTimeout) {
+		// shown verbatim, as with --raw, rather than not at all
+		m.renderTimedOut, m.lateRender = true, late
+		return strings.ReplaceAll(raw, "\r\n", "\n"), nil
+	}
+	return out, err
+}
+
+// errRenderTimeout is glamour taking longer than --render-timeout.
+var errRenderTimeout = errors.New("render timed out")
+
+// renderSlot is held by the render withDeadline is running. A render that
+// timed out keeps it until glamour finally returns; a resize or reload
+// meanwhile times out at once rather than piling another render on top.
+var renderSlot = make(chan struct{}, 1)
+
+// renderResult is what a render came to.
+type renderResult struct {
+	out string
+	err error
+}
+
+// withDeadline returns fn's result, or errRenderTimeout once d has passed
+// (0 = wait as long as it takes). Glamour can't be interrupted, so a
+// render that times out runs on in the background and late delivers its
+// result. At most one runs at a time: while an earlier one is still going
+// fn isn't started, and late is nil.
+func withDeadline(d time.Duration, fn func() (string, error)) (out string, late <-chan renderResult, err error) {
+	if d <= 0 {
+		out, err = fn()
+		return out, nil, err
+	}
+	select {
+	case renderSlot <- struct{}{}:
+	default:
+		return "", nil, errRenderTimeout
+	}
+	done := make(chan renderResult, 1)
+	go func() {
+		defer func() { <-renderSlot }()
+		out, err := fn()
+		done <- renderResult{out, err}
+	}()
+	deadline := time.NewTimer(d)
+	defer deadline.Stop()
+	select {
+	case r := <-done:
+		return r.out, nil, r.err
+	case <-deadline.C:
+		return "", done, errRenderTimeout
+	}
+}
+
+// renderTimeoutNote says what became of a render that timed out.
+func (m *model) renderTimeoutNote() string {
+	return fmt.Sprintf("rendering took over %s: showing the text unrendered (raise --render-timeout)", m.renderTimeout)
+}
+
+// rawMode reports whether the current file skips Markdown rendering:
+// always with --raw, and for scene files (.nfo, .diz, .ans).
+func (m *model) rawMode() bool {
+	return m.raw || isSceneFile(m.filename)
+}
+
+// emitOSC8 wraps the rendered text and URL of each external link in OSC 8
+// hyperlink escapes so capable terminals make them clickable. Each link is
+// looked for on its line after the one before it: the URL first, then the
+// text as the last copy ahead of the URL, so a repeated text or an earlier
+// plain word of the same spelling isn't taken for it.
+func emitOSC8(raw, rendered string) string {
+	lines := strings.Split(rendered, "\n")
+	plain := stripANSI(rendered)
+	plainLines := strings.Split(plain, "\n")
+	links, _ := parseLinks(raw, plain)
+	from := map[int]int{} // line -> byte offset in its plain text to look from
+	for _, l := range links {
+		li := l.renderedLine
+		if strings.HasPrefix(l.target, "#") || li < 0 || li >= len(lines) {
+			continue
+		}
+		open, end := "\x1b]8;;"+l.target+"\x1b\\", "\x1b]8;;\x1b\\"
+		pl, start := plainLines[li], from[li]
+		var spans [][2]int // byte ranges in pl
+		u := -1
+		if l.target != "" {
+			if u = strings.Index(pl[start:], l.target); u >= 0 {
+				u += start
+				spans = append(spans, [2]int{u, u + len(l.target)})
+			}
+		}
+		if l.text != "" && l.text != l.target {
+			var t int
+			if u >= 0 {
+				t = strings.LastIndex(pl[start:u], l.text)
+			} else {
+				t = strings.Index(pl[start:], l.text)
+			}
+			if t >= 0 {
+				t += start
+				spans = append(spans, [2]int{t, t + len(l.text)})
+			}
+		}
+		at := map[int]string{}
+		for _, sp := range spans {
+			col := utf8.RuneCountInString(pl[:sp[0]])
+			endCol := col + utf8.RuneCountInString(pl[sp[0]:sp[1]])
+			at[col] += open
+			at[endCol] = end + at[endCol]
+			from[li] = max(from[li], sp[1])
+		}
+		lines[li] = insertAtColumns(lines[li], at)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// insertAtColumns inserts at[c] before the visible rune at column c of line
+// (or at its end), stepping over existing ANSI sequences.
+func insertAtColumns(line string, at map[int]string) string {
+	if len(at) == 0 {
+		return line
+	}
+	seqs := ansiRE.FindAllStringIndex(line, -1)
+	var b strings.Builder
+	col := 0
+	for i := 0; i < len(line); {
+		if len(seqs) > 0 && i == seqs[0][0] {
+			b.WriteString(line[seqs[0][0]:seqs[0][1]])
+			i = seqs[0][1]
+			seqs = seqs[1:]
+			continue
+		}
+		b.WriteString(at[col])
+		r, n := utf8.DecodeRuneInString(line[i:])
+		b.WriteRune(r)
+		i += n
+		col++
+	}
+	b.WriteString(at[col])
+	return b.String()
+}
+
+// refreshContent rebuilds the visible lines from the current tx progress.
+func (m *model) refreshContent() {
+	if m.handshaking {
+		m.renderedLines = nil
+		m.totalLines = 0
+		m.view.SetContent(m.applyPostEffects(m.handshakeText(time.Now())))
+		return
+	}
+	if blank(m.markdown()) {
+		// say so, rather than leave a blank screen
+		note := "(empty)"
+		if !blank(m.rawMarkdown) {
+			note = "(front matter only)"
+		}
+		m.renderedLines = nil
+		m.totalLines = 0
+		m.view.SetContent(strings.Repeat("\n", max(0, (m.view.Height-1)/2)) +
+			strings.Repeat(" ", max(0, (m.view.Width-displayWidth(note))/2)) + note)
+		return
+	}
+	part := m.partialStreamString()
+	post := m.applyPostEffects(part)
+	m.renderedLines = strings.Split(strings.TrimRight(post, "\n"), "\n")
+	m.totalLines = len(m.renderedLines)
+	// the view shows a window of each line, starting at column xOffset
+	lines := make([]string, len(m.renderedLines))
+	pad := strings.Repeat(" ", m.margin())
+	for i, l := range m.renderedLines {
+		lines[i] = pad + cutColumns(l, m.xOffset, m.view.Width-m.gutter-len(pad))
+		if m.dimRead && i < m.readMark {
+			lines[i] = dimLine(lines[i])
+		}
+	}
+	if m.gutter > 0 {
+		m.view.SetContent(m.withGutter(lines))
+		return
+	}
+	m.view.SetContent(strings.Join(lines, "\n"))
+}
+
+// blank reports whether a document has nothing to show but whitespace.
+func blank(raw string) bool {
+	return strings.TrimSpace(raw) == ""
+}
+
+// applyPostEffects runs the render package's effects over rendered
+// output, with this session's marks and degauss in between.
+func (m *model) applyPostEffects(s string) string {
+	return render.Effects(s, m.effectOptions())
+}
+
+// effectOptions is the post effects as they stand: the toggles, the
+// terminal's colors, and the degauss animation, whose brief flash at the
+// start flips reverse video for a moment either way.
+func (m *model) effectOptions() render.Options {
+	flash := m.degauss > 0 && m.degauss > m.degaussTotalFrames()-m.degaussFlashFrames()
+	return render.Options{
+		Mono:       m.mono,
+		TrueColor:  m.truecolor,
+		Palette256: m.palette256,
+		Bloom:      m.bloom,
+		Scanlines:  m.scanlines || m.degauss > 0,
+		Inverse:    m.inverse != flash,
+		NoColor:    m.noColor,
+		Overlay:    m.overlay,
+	}
+}
+
+// overlay draws the search and link highlights, then the degauss jitter
+// and, when stronger, color wobble (after the highlights, so their
+// columns line up).
+func (m *model) overlay(s string) string {
+	s = m.applySearch(s)
+	s = m.applyLinkMarks(s)
+	if m.degauss == 0 {
+		return s
+	}
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	fade := float64(m.degauss) / float64(m.degaussTotalFrames())
+	for i := range lines {
+		if m.degaussStrength > 1 && m.rand.Float64() < fade*0.15*float64(m.degaussStrength) {
+			shift := (m.rand.Float64()*2 - 1) * fade * 0.2 * float64(m.degaussStrength-1)
+			lines[i] = render.Wobble(lines[i], m.mono, shift, m.truecolor, m.palette256)
+		}
+		if m.rand.Intn(3) == 0 {
+			if off := m.rand.Intn(m.degaussStrength + 1); off > 0 {
+				lines[i] = strings.Repeat(" ", off) + lines[i]
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// charWrap breaks every line of s at w columns, mid-word if need be.
+// Continuation lines keep the line's indent, so text stays inside the
+// document margin.
+func charWrap(s string, w int) string {
+	var out []string
+	for _, line := range strings.Split(s, "\n") {
+		plain := strings.TrimRight(stripANSI(line), " ")
+		n := displayWidth(plain)
+		if n <= w {
+			out = append(out, line)
+			continue
+		}
+		indent := len(plain) - len(strings.TrimLeft(plain, " "))
+		if indent >= w/2 {
+			indent = 0
+		}
+		out = append(out, cutColumns(line, 0, w))
+		for from := w; from < n; from += w - indent {
+			out = append(out, strings.Repeat(" ", indent)+cutColumns(line, from, w-indent))
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// dimLine draws line faint, renewing the dim after each reset glamour
+// puts between its styled spans.
+func dimLine(line string) string {
+	return "\x1b[2m" + strings.ReplaceAll(line, "\x1b[0m", "\x1b[0;2m") + "\x1b[22m"
+}
+
+// cutColumns returns the w columns of line starting at column from. Every
+// ANSI sequence is kept so colors and hyperlinks carry across the edges; a
+// wide rune split by the left edge becomes a space.
+func cutColumns(line string, from, w int) string {
+	if from == 0 && displayWidth(stripANSI(line)) <= w {
+		return line
+	}
+	seqs := ansiRE.FindAllStringIndex(line, -1)
+	var b strings.Builder
+	col := 0
+	for i := 0; i < len(line); {
+		if len(seqs) > 0 && i == seqs[0][0] {
+			b.WriteString(line[seqs[0][0]:seqs[0][1]])
+			i = seqs[0][1]
+			seqs = seqs[1:]
+			continue
+		}
+		r, n := utf8.DecodeRuneInString(line[i:])
+		i += n
+		rw := runewidth.RuneWidth(r)
+		switch {
+		case col >= from && col+rw <= from+w:
+			b.WriteRune(r)
+		case col < from && col+rw > from:
+			b.WriteByte(' ')
+		}
+		col += rw
+	}
+	return b.String()
+}
+
+// visibleLineWidth is the width of the widest line currently on screen.
+func (m *model) visibleLineWidth() int {
+	w := 0
+	end := min(len(m.renderedLines), m.view.YOffset+m.view.Height)
+	for i := max(0, m.view.YOffset); i < end; i++ {
+		w = max(w, displayWidth(stripANSI(m.renderedLines[i])))
+	}
+	return w
+}
+
+// pan scrolls the view sideways by delta columns, no further than the
+// widest visible line needs.
+func (m *model) pan(delta int) {
+	limit := max(0, m.visibleLineWidth()-(m.view.Width-m.gutter-m.margin()))
+	off := clamp(m.xOffset+delta, 0, limit)
+	if off != m.xOffset {
+		m.xOffset = off
+		m.refreshContent()
+	}
+}
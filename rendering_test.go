@@ -0,0 +1,205 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCutColumns(t *testing.T) {
+	tests := []struct {
+		in      string
+		from, w int
+		want    string
+	}{
+		{"abcdef", 2, 3, "cde"},
+		{"日本語", 0, 4, "日本"},
+		{"日本語", 0, 3, "日"},  // 本 would straddle the right edge
+		{"日本語", 1, 4, " 本"}, // 日 is split by the left edge
+		{"a🙂b", 1, 2, "🙂"},
+		{"\x1b[1m日本\x1b[0m", 2, 2, "\x1b[1m本\x1b[0m"},
+	}
+	for _, tt := range tests {
+		if got := cutColumns(tt.in, tt.from, tt.w); got != tt.want {
+			t.Errorf("cutColumns(%q, %d, %d) = %q, want %q", tt.in, tt.from, tt.w, got, tt.want)
+		}
+	}
+}
+
+// TestRenderKey checks that each setting that changes the render changes
+// the cache key, so a render made with it is never reused without it.
+func TestRenderKey(t *testing.T) {
+	changes := map[string]func(m *model){
+		"wrap":            func(m *model) { m.wrapWidth = 40 },
+		"wrap mode":       func(m *model) { m.wrapMode = wrapChar },
+		"theme":           func(m *model) { m.theme = "light" },
+		"code theme":      func(m *model) { m.codeTheme = "monokai" },
+		"raw":             func(m *model) { m.raw = true },
+		"80x25":           func(m *model) { m.fixed8025 = true },
+		"osc8":            func(m *model) { m.osc8 = true },
+		"preserve breaks": func(m *model) { m.preserveBreaks = true },
+		"quote bar":       func(m *model) { m.quoteBar = '>' },
+		"quote colors":    func(m *model) { m.quoteColors = []string{"39", "170"} },
+		"tab width":       func(m *model) { m.tabWidth = 8 },
+		"front matter":    func(m *model) { m.frontMatter = frontMatterRaw },
+		"section":         func(m *model) { m.section = "setup" },
+		"diff":            func(m *model) { m.diffName = "old.md" },
+	}
+	for name, change := range changes {
+		m := docModel(dupDoc, "dark")
+		before := m.renderKey(100)
+		change(&m)
+		if m.renderKey(100) == before {
+			t.Errorf("%s: the render key doesn't change", name)
+		}
+	}
+}
+
+// TestWithDeadline times out a slow render stub and checks no other
+// render starts, or waits, until it ends.
+func TestWithDeadline(t *testing.T) {
+	fast := func() (string, error) { return "done", nil }
+	if out, late, err := withDeadline(time.Second, fast); out != "done" || late != nil || err != nil {
+		t.Fatalf("fast render: %q, %v, %v", out, late, err)
+	}
+
+	// a slow render times out, and is still running afterwards
+	release := make(chan struct{})
+	var started atomic.Int32
+	slow := func() (string, error) {
+		started.Add(1)
+		<-release
+		return "late", nil
+	}
+	_, late, err := withDeadline(20*time.Millisecond, slow)
+	if !errors.Is(err, errRenderTimeout) || late == nil {
+		t.Fatalf("slow render: err = %v, late %v; want errRenderTimeout and the late result", err, late)
+	}
+	// until it ends, no other render starts or waits for it
+	for range 3 {
+		start := time.Now()
+		_, l, err := withDeadline(10*time.Second, slow)
+		if !errors.Is(err, errRenderTimeout) || l != nil {
+			t.Fatalf("render behind a stuck one: err = %v, late %v; want errRenderTimeout and none", err, l)
+		}
+		if d := time.Since(start); d > time.Second {
+			t.Errorf("render behind a stuck one waited %v", d)
+		}
+	}
+	if n := started.Load(); n != 1 {
+		t.Errorf("%d renders started, want 1 in flight", n)
+	}
+	close(release)
+	if r := <-late; r.out != "late" || r.err != nil {
+		t.Errorf("late result %+v", r)
+	}
+	if out, _, err := withDeadline(time.Second, fast); out != "done" || err != nil {
+		t.Errorf("render after the stuck one ended: %q, %v", out, err)
+	}
+}
+
+// TestRenderTimeoutCached checks the unrendered fallback is kept while
+// glamour is stuck, and replaced when the late render comes in.
+func TestRenderTimeoutCached(t *testing.T) {
+	raw := "# Title\n\nSome *text*.\n"
+	m := docModel(raw, "dark")
+	m.renderTimeout = 20 * time.Millisecond
+	renderSlot <- struct{}{} // a stuck render holds the slot
+	m.recalcRendered(80, 24)
+	if !strings.Contains(m.renderedFull, "*text*") || !m.cache.valid || !m.cache.timedOut {
+		t.Fatalf("cache %+v, output %q; want the raw text cached as timed out", m.cache, m.renderedFull)
+	}
+	m.renderedFull = ""
+	m.recalcRendered(80, 24)
+	if !strings.Contains(m.renderedFull, "*text*") || m.renderTimedOut {
+		t.Errorf("second render: timed out again %v, output %q; want the cached fallback", m.renderTimedOut, m.renderedFull)
+	}
+
+	// a late render of something else: render again, now glamour is free
+	<-renderSlot
+	stale := renderDoneMsg{key: m.cache.key, raw: "# Old\n", renderResult: renderResult{out: "old"}}
+	m.finishRender(stale)
+	if strings.Contains(m.renderedFull, "*text*") || strings.Contains(m.renderedFull, "old") || m.cache.timedOut {
+		t.Errorf("after a stale late render: cache %+v, output %q", m.cache, m.renderedFull)
+	}
+
+	// the late render for what is on screen replaces the fallback
+	renderSlot <- struct{}{}
+	m.cache.valid = false
+	m.recalcRendered(80, 24)
+	<-renderSlot
+	m.finishRender(renderDoneMsg{key: m.cache.key, raw: m.markdown(), renderResult: renderResult{out: "rendered\n"}})
+	if m.renderedFull != "rendered\n" || m.cache.timedOut {
+		t.Errorf("after the late render: cache %+v, output %q", m.cache, m.renderedFull)
+	}
+}
+
+// BenchmarkRender compares a render that misses the cache, as after a
+// resize or a style change, with one that hits it, as after toggling a
+// post effect; both apply the post effects, as recalcRendered does.
+func BenchmarkRender(b *testing.B) {
+	readme, err := os.ReadFile("README.md")
+	if err != nil {
+		b.Fatal(err)
+	}
+	m := docModel(strings.Repeat(string(readme), 8), "dark")
+	m.scanlines = true
+	b.Run("miss", func(b *testing.B) {
+		for range b.N {
+			m.cache.valid = false
+			out, err := m.renderDocument(100)
+			if err != nil {
+				b.Fatal(err)
+			}
+			m.applyPostEffects(out)
+		}
+	})
+	b.Run("hit", func(b *testing.B) {
+		if _, err := m.renderDocument(100); err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		for range b.N {
+			out, err := m.renderDocument(100)
+			if err != nil {
+				b.Fatal(err)
+			}
+			m.applyPostEffects(out)
+		}
+	})
+}
+
+func TestEmitOSC8(t *testing.T) {
+	link := func(url, text string) string { return "\x1b]8;;" + url + "\x1b\\" + text + "\x1b]8;;\x1b\\" }
+	tests := []struct {
+		name, raw, rendered, want string
+	}{
+		{
+			"same text twice",
+			"[go](https://a.example) and [go](https://b.example)\n",
+			"go https://a.example and go https://b.example",
+			link("https://a.example", "go") + " " + link("https://a.example", "https://a.example") + " and " +
+				link("https://b.example", "go") + " " + link("https://b.example", "https://b.example"),
+		},
+		{
+			"earlier plain word",
+			"go to [go](https://a.example)\n",
+			"go to go https://a.example",
+			"go to " + link("https://a.example", "go") + " " + link("https://a.example", "https://a.example"),
+		},
+		{
+			"bare url",
+			"see https://a.example here\n",
+			"see https://a.example here",
+			"see " + link("https://a.example", "https://a.example") + " here",
+		},
+	}
+	for _, tt := range tests {
+		if got := emitOSC8(tt.raw, tt.rendered); got != tt.want {
+			t.Errorf("%s:\n got %q\nwant %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ---------- search ----------
+
+type searchMatch struct {
+	line   int // rendered line
+	col    int // rune column in the plain (ANSI-stripped) line
+	length int // in runes
+}
+
+// updateInput edits the footer input line; Enter hands the text to the
+// mode's command, Esc discards it.
+func (m *model) updateInput(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.mode, m.input = modeNormal, ""
+	case tea.KeyEnter:
+		mode, input := m.mode, m.input
+		m.mode, m.input = modeNormal, ""
+		switch mode {
+		case modeSearch:
+			m.commitSearch(input)
+		case modeGoto:
+			return m.gotoLine(input)
+		}
+	case tea.KeyBackspace:
+		if r := []rune(m.input); len(r) > 0 {
+			m.input = string(r[:len(r)-1])
+		}
+	case tea.KeySpace:
+		m.input += " "
+	case tea.KeyRunes:
+		m.input += string(msg.Runes)
+	}
+	return nil
+}
+
+// gotoLine jumps like less: "120" to rendered line 120, "50%" halfway.
+func (m *model) gotoLine(s string) tea.Cmd {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	maxOff := max(0, m.totalLines-m.view.Height)
+	off := 0
+	if pct, ok := strings.CutSuffix(s, "%"); ok {
+		p, err := strconv.ParseFloat(strings.TrimSpace(pct), 64)
+		if err != nil {
+			m.flash("bad percentage: " + s)
+			return m.tick()
+		}
+		off = int(p / 100 * float64(maxOff))
+	} else {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			m.flash("bad line number: " + s)
+			return m.tick()
+		}
+		off = n - 1
+	}
+	m.txBlink = 6
+	m.animating = false
+	m.record()
+	m.view.SetYOffset(clamp(off, 0, maxOff))
+	return nil
+}
+
+// startAt opens the viewer at --start-at: a line number, a percentage or
+// a heading anchor (# optional). The handshake and stream are skipped, as
+// the place may not have arrived yet.
+func (m *model) startAt(where string) {
+	if m.handshaking {
+		m.endHandshake()
+	}
+	m.skipStream()
+	if _, err := strconv.ParseFloat(strings.TrimSuffix(where, "%"), 64); err == nil {
+		m.gotoLine(where)
+		return
+	}
+	if !m.jumpToAnchor(strings.TrimPrefix(where, "#"), -1) {
+		m.flash(fmt.Sprintf("--start-at %s: no such heading, starting at the top", where))
+	}
+}
+
+func (m *model) commitSearch(q string) {
+	m.searchQuery = q
+	m.searchIndex = 0
+	m.refreshContent()
+	m.scrollToMatch()
+}
+
+// nextMatch moves the active match by dir (+1/-1), wrapping around.
+func (m *model) nextMatch(dir int) {
+	n := len(m.searchMatches)
+	if n == 0 {
+		return
+	}
+	m.searchIndex = (m.searchIndex + dir + n) % n
+	m.refreshContent()
+	m.scrollToMatch()
+}
+
+// searchBadge is the footer's place in the search results, e.g. [3/17],
+// while a search is active.
+func (m model) searchBadge() string {
+	switch {
+	case m.searchQuery == "":
+		return ""
+	case len(m.searchMatches) == 0:
+		return "no matches"
+	}
+	return fmt.Sprintf("[%d/%d]", m.searchIndex+1, len(m.searchMatches))
+}
+
+func (m *model) scrollToMatch() {
+	if m.searchIndex < 0 || m.searchIndex >= len(m.searchMatches) {
+		return
+	}
+	target := m.searchMatches[m.searchIndex].line - m.view.Height/2
+	m.view.SetYOffset(clamp(target, 0, max(0, m.totalLines-m.view.Height)))
+}
+
+// findMatches returns case-insensitive matches of q in the plain lines.
+func findMatches(lines []string, q string) []searchMatch {
+	needle := []rune(strings.ToLower(q))
+	if len(needle) == 0 {
+		return nil
+	}
+	var out []searchMatch
+	for li, line := range lines {
+		hay := []rune(line)
+		for i := range hay {
+			hay[i] = unicode.ToLower(hay[i])
+		}
+		for col := 0; col+len(needle) <= len(hay); {
+			if string(hay[col:col+len(needle)]) == string(needle) {
+				out = append(out, searchMatch{line: li, col: col, length: len(needle)})
+				col += len(needle)
+				continue
+			}
+			col++
+		}
+	}
+	return out
+}
+
+// applySearch recomputes matches against the plain text of s and marks them:
+// the active match in inverse video, the rest underlined.
+func (m *model) applySearch(s string) string {
+	if m.searchQuery == "" {
+		m.searchMatches = nil
+		return s
+	}
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	plain := strings.Split(stripANSI(strings.Join(lines, "\n")), "\n")
+	m.searchMatches = findMatches(plain, m.searchQuery)
+	if m.searchIndex >= len(m.searchMatches) {
+		m.searchIndex = 0
+	}
+
+	// matches are ordered by line, so each line's spans are contiguous
+	for start := 0; start < len(m.searchMatches); {
+		li := m.searchMatches[start].line
+		end := start
+		for end < len(m.searchMatches) && m.searchMatches[end].line == li {
+			end++
+		}
+		if li < len(lines) {
+			lines[li] = highlightSpans(lines[li], m.searchMatches[start:end], m.searchIndex-start)
+		}
+		start = end
+	}
+	return strings.Join(lines, "\n")
+}
+
+// mergeSpans sorts spans by column, as highlightSpans needs them, and
+// joins those that overlap; active is the index of the selected span
+// before and after.
+func mergeSpans(spans []searchMatch, active int) ([]searchMatch, int) {
+	order := make([]int, len(spans))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return spans[order[i]].col < spans[order[j]].col })
+	var out []searchMatch
+	newActive := -1
+	for _, i := range order {
+		sp := spans[i]
+		if n := len(out); n > 0 && sp.col < out[n-1].col+out[n-1].length {
+			last := &out[n-1]
+			last.length = max(last.length, sp.col+sp.length-last.col)
+		} else {
+			out = append(out, sp)
+		}
+		if i == active {
+			newActive = len(out) - 1
+		}
+	}
+	return out, newActive
+}
+
+// highlightSpans wraps rune ranges of the visible text of line in SGR,
+// skipping over (and re-opening after) any ANSI sequences already present.
+// spans[active] gets inverse video; the others are underlined.
+func highlightSpans(line string, spans []searchMatch, active int) string {
+	open := func(i int) string {
+		if i == active {
+			return "\x1b[7m"
+		}
+		return "\x1b[4m"
+	}
+	closeSGR := func(i int) string {
+		if i == active {
+			return "\x1b[27m"
+		}
+		return "\x1b[24m"
+	}
+
+	seqs := ansiRE.FindAllStringIndex(line, -1)
+	var b strings.Builder
+	col, next, cur := 0, 0, -1 // cur = index of the span we're inside
+	for i := 0; i < len(line); {
+		if len(seqs) > 0 && i == seqs[0][0] {
+			b.WriteString(line[seqs[0][0]:seqs[0][1]])
+			i = seqs[0][1]
+			seqs = seqs[1:]
+			if cur >= 0 {
+				b.WriteString(open(cur))
+			}
+			continue
+		}
+		if cur < 0 && next < len(spans) && col == spans[next].col {
+			cur = next
+			next++
+			b.WriteString(open(cur))
+		}
+		r, n := utf8.DecodeRuneInString(line[i:])
+		b.WriteRune(r)
+		i += n
+		col++
+		if cur >= 0 && col == spans[cur].col+spans[cur].length {
+			b.WriteString(closeSGR(cur))
+			cur = -1
+		}
+	}
+	if cur >= 0 {
+		b.WriteString(closeSGR(cur))
+	}
+	return b.String()
+}
+
+// applyLinkMarks underlines every link's text when link highlighting (L)
+// is on, with the selected link in inverse video.
+func (m *model) applyLinkMarks(s string) string {
+	if !m.highlightLinks || len(m.links) == 0 {
+		return s
+	}
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	byLine := map[int][]searchMatch{}
+	active := map[int]int{} // line -> index of the selected link's span
+	from := map[int]int{}   // line -> byte offset to look from, for repeats
+	for i, l := range m.links {
+		li := l.renderedLine
+		if li < 0 || li >= len(lines) || l.text == "" {
+			continue
+		}
+		// the line is where the URL landed; the text before it may have
+		// wrapped onto the line above. After the spans already found there
+		// first, for repeats, then anywhere on it.
+		find := func(li int, anywhere bool) (string, int) {
+			plain, start := stripANSI(lines[li]), from[li]
+			if anywhere {
+				start = 0
+			}
+			if p := strings.Index(plain[start:], l.text); p >= 0 {
+				return plain, start + p
+			}
+			return plain, -1
+		}
+		var plain string
+		p := -1
+		for _, anywhere := range []bool{false, true} {
+			if plain, p = find(li, anywhere); p < 0 && li > 0 {
+				if plain, p = find(li-1, anywhere); p >= 0 {
+					li--
+				}
+			}
+			if p >= 0 {
+				break
+			}
+		}
+		if p < 0 {
+			continue
+		}
+		from[li] = max(from[li], p+len(l.text))
+		if i == m.linkIndex {
+			active[li] = len(byLine[li])
+		}
+		byLine[li] = append(byLine[li], searchMatch{
+			line:   li,
+			col:    utf8.RuneCountInString(plain[:p]),
+			length: utf8.RuneCountInString(l.text),
+		})
+	}
+	for li, spans := range byLine {
+		a, ok := active[li]
+		if !ok {
+			a = -1
+		}
+		spans, a = mergeSpans(spans, a)
+		lines[li] = highlightSpans(lines[li], spans, a)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeSpans(t *testing.T) {
+	sp := func(col, length int) searchMatch { return searchMatch{col: col, length: length} }
+	tests := []struct {
+		name       string
+		in         []searchMatch
+		active     int
+		want       []searchMatch
+		wantActive int
+	}{
+		{"sorted", []searchMatch{sp(0, 2), sp(5, 3)}, 1, []searchMatch{sp(0, 2), sp(5, 3)}, 1},
+		{"out of order", []searchMatch{sp(6, 4), sp(0, 5)}, 0, []searchMatch{sp(0, 5), sp(6, 4)}, 1},
+		{"overlap", []searchMatch{sp(4, 4), sp(0, 6)}, 0, []searchMatch{sp(0, 8)}, 0},
+		{"inside", []searchMatch{sp(0, 10), sp(2, 3)}, -1, []searchMatch{sp(0, 10)}, -1},
+		{"touching", []searchMatch{sp(3, 2), sp(0, 3)}, 1, []searchMatch{sp(0, 3), sp(3, 2)}, 0},
+	}
+	for _, tt := range tests {
+		got, a := mergeSpans(tt.in, tt.active)
+		if !reflect.DeepEqual(got, tt.want) || a != tt.wantActive {
+			t.Errorf("%s: %v, active %d; want %v, %d", tt.name, got, a, tt.want, tt.wantActive)
+		}
+	}
+}
+
+// TestApplyLinkMarksOrder has two links on one line, the second in the
+// list coming first on the line.
+func TestApplyLinkMarksOrder(t *testing.T) {
+	m := testModel(2, 5)
+	m.highlightLinks = true
+	m.links = []link{
+		{text: "beta", target: "https://b", renderedLine: 0},
+		{text: "alpha", target: "https://a", renderedLine: 0},
+	}
+	tests := []struct {
+		active int
+		want   string
+	}{
+		{-1, "\x1b[4malpha\x1b[24m and \x1b[4mbeta\x1b[24m\nnext"},
+		{0, "\x1b[4malpha\x1b[24m and \x1b[7mbeta\x1b[27m\nnext"},
+		{1, "\x1b[7malpha\x1b[27m and \x1b[4mbeta\x1b[24m\nnext"},
+	}
+	for _, tt := range tests {
+		m.linkIndex = tt.active
+		if got := m.applyLinkMarks("alpha and beta\nnext"); got != tt.want {
+			t.Errorf("selected %d: %q, want %q", tt.active, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import "strings"
+
+// ---------- one section (--section) ----------
+
+// findSection is the index in hs of the heading name refers to: an anchor
+// as --toc lists it, with or without the #, or failing that a heading whose
+// text slugs the same; -1 when there is none.
+func findSection(hs []heading, name string) int {
+	anc := strings.TrimPrefix(strings.TrimSpace(name), "#")
+	// exact anchors first, so setup-1 is the second "Setup"
+	for i, h := range hs {
+		if h.anchor == anc {
+			return i
+		}
+	}
+	for i, h := range hs {
+		if slugify(h.text) == anc || slugify(anc) == h.anchor {
+			return i
+		}
+	}
+	return -1
+}
+
+// sectionBody is raw with everything outside name's section blanked out:
+// the section runs from its heading to the next heading of the same or a
+// higher level. Link reference definitions are kept wherever they are, so
+// [text][ref] links in the section still resolve. Like markdownBody,
+// blanking keeps byte offsets and line numbers. ok is false when raw has
+// no such heading.
+func sectionBody(raw, name string) (body string, ok bool) {
+	hs := parseHeadings(raw, false)
+	i := findSection(hs, name)
+	if i < 0 {
+		return raw, false
+	}
+	from, to := hs[i].line, -1
+	for _, h := range hs[i+1:] {
+		if h.level <= hs[i].level {
+			to = h.line
+			break
+		}
+	}
+
+	defs := map[int]bool{}
+	for n, l := range strings.SplitAfter(raw, "\n") {
+		defs[n] = reRefDef.MatchString(l)
+	}
+
+	b := []byte(raw)
+	line := 0
+	for k, c := range b {
+		if c == '\n' {
+			line++
+			continue
+		}
+		if c != '\r' && !defs[line] && (line < from || to >= 0 && line >= to) {
+			b[k] = ' '
+		}
+	}
+	return string(b), true
+}
+
+// sectionOf is sectionBody for name, or all of raw when name is empty or
+// not found (an edited file that lost the heading, a --diff base without
+// it).
+func sectionOf(raw, name string) string {
+	if name == "" {
+		return raw
+	}
+	body, _ := sectionBody(raw, name)
+	return body
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSectionKeepsRefDefs(t *testing.T) {
+	raw := "# Doc\n\n## Install\n\nGet it from [the site][site] or [Go][].\n\n## Usage\n\nRun it.[^1]\n\n[site]: https://example.com\n  [go]: https://go.dev\n[^1]: A footnote.\n"
+	body, ok := sectionBody(raw, "install")
+	if !ok {
+		t.Fatal("no section install")
+	}
+	if len(body) != len(raw) || strings.Count(body, "\n") != strings.Count(raw, "\n") {
+		t.Errorf("offsets moved: %q", body)
+	}
+	for _, want := range []string{"## Install", "[the site][site]", "[site]: https://example.com", "  [go]: https://go.dev"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("%q blanked from %q", want, body)
+		}
+	}
+	for _, gone := range []string{"# Doc", "Usage", "Run it", "A footnote"} {
+		if strings.Contains(body, gone) {
+			t.Errorf("%q kept in %q", gone, body)
+		}
+	}
+	links, _ := parseLinks(body, "Get it from the site https://example.com or Go https://go.dev.\n")
+	if len(links) != 2 || links[0].target != "https://example.com" || links[1].target != "https://go.dev" {
+		t.Errorf("links in the section: %+v", links)
+	}
+}
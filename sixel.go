@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"strings"
+)
+
+// ---------- SIXEL images ----------
+
+// sixelCellWidth and sixelCellHeight are the assumed pixel size of a
+// character cell, for sizing images in rows; terminals commonly use about
+// 10x20.
+const (
+	sixelCellWidth  = 10
+	sixelCellHeight = 20
+)
+
+// sixelEscape scales img to pw x ph pixels, dithers it to a 256-color
+// palette and encodes it as SIXEL. Mostly transparent pixels are left
+// unpainted. The cursor is saved and restored around it, as kitty's C=1
+// does, so the rows reserved below the image line up.
+func sixelEscape(img image.Image, pw, ph int) string {
+	b := img.Bounds()
+	scaled := image.NewRGBA(image.Rect(0, 0, pw, ph))
+	for y := 0; y < ph; y++ {
+		for x := 0; x < pw; x++ {
+			scaled.Set(x, y, img.At(b.Min.X+x*b.Dx()/pw, b.Min.Y+y*b.Dy()/ph))
+		}
+	}
+	pal := image.NewPaletted(scaled.Bounds(), palette.Plan9)
+	draw.FloydSteinberg.Draw(pal, pal.Bounds(), scaled, image.Point{})
+
+	var s strings.Builder
+	// P2=1: pixels no color is drawn on keep the background
+	fmt.Fprintf(&s, "\x1b7\x1bP0;1;0q\"1;1;%d;%d", pw, ph)
+	var used [256]bool
+	for i, c := range pal.Pix {
+		if scaled.Pix[i*4+3] >= 0x80 {
+			used[c] = true
+		}
+	}
+	for i, c := range pal.Palette {
+		if used[i] {
+			r, g, bl, _ := c.RGBA()
+			fmt.Fprintf(&s, "#%d;2;%d;%d;%d", i, r*100/0xffff, g*100/0xffff, bl*100/0xffff)
+		}
+	}
+
+	// one band of six pixel rows at a time, one pass per color in it
+	row := make([]byte, pw)
+	for y0 := 0; y0 < ph; y0 += 6 {
+		var inBand [256]bool
+		for y := y0; y < min(y0+6, ph); y++ {
+			for x := 0; x < pw; x++ {
+				if scaled.Pix[scaled.PixOffset(x, y)+3] >= 0x80 {
+					inBand[pal.Pix[pal.PixOffset(x, y)]] = true
+				}
+			}
+		}
+		first := true
+		for c := range inBand {
+			if !inBand[c] {
+				continue
+			}
+			for x := range row {
+				var bits byte
+				for dy := 0; dy < 6 && y0+dy < ph; dy++ {
+					y := y0 + dy
+					if int(pal.Pix[pal.PixOffset(x, y)]) == c && scaled.Pix[scaled.PixOffset(x, y)+3] >= 0x80 {
+						bits |= 1 << dy
+					}
+				}
+				row[x] = '?' + bits
+			}
+			if !first {
+				s.WriteByte('$') // back to the start of the band
+			}
+			first = false
+			fmt.Fprintf(&s, "#%d", c)
+			writeSixelRuns(&s, row)
+		}
+		s.WriteByte('-')
+	}
+	s.WriteString("\x1b\\\x1b8")
+	return s.String()
+}
+
+// writeSixelRuns writes a band row with runs of four or more collapsed
+// to !<count><char>; trailing empty sixels are dropped.
+func writeSixelRuns(s *strings.Builder, row []byte) {
+	row = []byte(strings.TrimRight(string(row), "?"))
+	for i := 0; i < len(row); {
+		j := i
+		for j < len(row) && row[j] == row[i] {
+			j++
+		}
+		if n := j - i; n >= 4 {
+			fmt.Fprintf(s, "!%d%c", n, row[i])
+		} else {
+			s.Write(row[i:j])
+		}
+		i = j
+	}
+}
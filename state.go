@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ---------- bookmarks (M / ') ----------
+
+// viewerState is what mdnfo remembers between runs, per document.
+type viewerState struct {
+	Files map[string]fileState `json:"files"`
+}
+
+type fileState struct {
+	Bookmarks map[int]int `json:"bookmarks,omitempty"` // digit -> YOffset
+}
+
+// defaultStatePath is state.json in configDir, next to the default
+// config file.
+func defaultStatePath() string {
+	dir := configDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, "state.json")
+}
+
+// loadState reads the state file; a missing or unreadable one is empty.
+func loadState(path string) viewerState {
+	st := viewerState{Files: map[string]fileState{}}
+	if path == "" {
+		return st
+	}
+	if b, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(b, &st)
+	}
+	if st.Files == nil {
+		st.Files = map[string]fileState{}
+	}
+	return st
+}
+
+func saveState(path string, st viewerState) error {
+	if path == "" {
+		return fmt.Errorf("no config directory")
+	}
+	b, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// write then rename, so a crash never leaves half a file
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// loadBookmarks reads the current document's bookmarks; stdin has none
+// saved.
+func (m *model) loadBookmarks() {
+	m.bookmarks = map[int]int{}
+	if m.filename == stdinName {
+		return
+	}
+	for n, off := range loadState(m.statePath).Files[m.filename].Bookmarks {
+		m.bookmarks[n] = off
+	}
+}
+
+// setBookmark puts bookmark n at the current position and saves it.
+func (m *model) setBookmark(n int) {
+	m.bookmarks[n] = m.view.YOffset
+	if m.filename != stdinName {
+		st := loadState(m.statePath)
+		st.Files[m.filename] = fileState{Bookmarks: m.bookmarks}
+		if err := saveState(m.statePath, st); err != nil {
+			m.flash(fmt.Sprintf("bookmark %d set, not saved: %v", n, err))
+			return
+		}
+	}
+	m.flash(fmt.Sprintf("bookmark %d: line %d", n, m.view.YOffset+1))
+}
+
+// jumpToBookmark scrolls to bookmark n, as a jump Backspace can undo.
+func (m *model) jumpToBookmark(n int) {
+	off, ok := m.bookmarks[n]
+	if !ok {
+		m.flash(fmt.Sprintf("no bookmark %d", n))
+		return
+	}
+	// whole document, or the mark may not have arrived yet
+	m.skipStream()
+	m.txBlink = 6
+	m.animating = false
+	m.record()
+	m.view.SetYOffset(clamp(off, 0, max(0, m.totalLines-m.view.Height)))
+}
+
+// updateBookmark takes the digit after M or '; any other key cancels.
+func (m *model) updateBookmark(msg tea.KeyMsg) tea.Cmd {
+	mode := m.mode
+	m.mode = modeNormal
+	n, err := strconv.Atoi(msg.String())
+	if err != nil || n < 0 || n > 9 {
+		return nil
+	}
+	if mode == modeMark {
+		m.setBookmark(n)
+	} else {
+		m.jumpToBookmark(n)
+	}
+	return m.tick()
+}
+
+// bookmarkBadge lists the set bookmarks for the header, e.g. "Marks:1,3".
+func (m *model) bookmarkBadge() string {
+	if len(m.bookmarks) == 0 {
+		return ""
+	}
+	ns := make([]int, 0, len(m.bookmarks))
+	for n := range m.bookmarks {
+		ns = append(ns, n)
+	}
+	sort.Ints(ns)
+	s := make([]string, len(ns))
+	for i, n := range ns {
+		s[i] = strconv.Itoa(n)
+	}
+	return "Marks:" + strings.Join(s, ",")
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ---------- streaming / baud emulation ----------
+
+func (m *model) prepareStreamTokens() {
+	// Tokenize renderedFull into ANSI and plain segments
+	s := m.renderedFull
+	m.streamTokens = m.streamTokens[:0]
+	m.streamTotalBytes = 0
+
+	idxs := ansiRE.FindAllStringIndex(s, -1)
+	last := 0
+	for _, span := range idxs {
+		// plain before ANSI
+		if span[0] > last {
+			chunk := s[last:span[0]]
+			if chunk != "" {
+				bt := len([]byte(chunk))
+				m.streamTokens = append(m.streamTokens, token{s: chunk, isANSI: false, byteLen: bt})
+				m.streamTotalBytes += bt
+			}
+		}
+		// the ANSI token
+		seq := s[span[0]:span[1]]
+		bt := len([]byte(seq))
+		m.streamTokens = append(m.streamTokens, token{s: seq, isANSI: true, byteLen: bt})
+		m.streamTotalBytes += bt
+		last = span[1]
+	}
+	// tail plain
+	if last < len(s) {
+		chunk := s[last:]
+		bt := len([]byte(chunk))
+		m.streamTokens = append(m.streamTokens, token{s: chunk, isANSI: false, byteLen: bt})
+		m.streamTotalBytes += bt
+	}
+
+	// (Re)start stream timing if not already started or if we re-rendered
+	if m.txStart.IsZero() {
+		m.txStart = time.Now()
+	}
+	// bytesPerSecond from baudrate with 8N1 overhead ~10 bits/byte
+	if m.baudrate > 0 {
+		m.bytesPerSecond = float64(m.baudrate) / 10.0
+	} else {
+		m.bytesPerSecond = 0
+	}
+	// If baudrate <= 0, show all immediately
+	if m.bytesPerSecond <= 0 {
+		m.txBytesAvailable = m.streamTotalBytes
+		m.streamDone = true
+	} else if m.txBytesAvailable > m.streamTotalBytes {
+		m.txBytesAvailable = m.streamTotalBytes
+		m.streamDone = true
+	}
+}
+
+func (m *model) partialStreamString() string {
+	if m.bytesPerSecond <= 0 {
+		return m.renderedFull
+	}
+	// Calculate allowed bytes based on elapsed time (frozen while paused)
+	elapsed := time.Since(m.txStart).Seconds()
+	if m.paused {
+		elapsed = m.pausedAt.Sub(m.txStart).Seconds()
+	}
+	allowed := int(elapsed * m.bytesPerSecond)
+	if allowed > m.streamTotalBytes {
+		allowed = m.streamTotalBytes
+	}
+	if allowed < 0 {
+		allowed = 0
+	}
+
+	// Blink RX if new bytes arrived
+	if allowed > m.txLastAvail {
+		m.rxBlink = 6
+	}
+	m.txLastAvail = allowed
+	m.txBytesAvailable = allowed
+	m.streamDone = allowed >= m.streamTotalBytes
+
+	if allowed == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	remain := allowed
+	for _, tk := range m.streamTokens {
+		if remain <= 0 {
+			break
+		}
+		if tk.byteLen <= remain {
+			b.WriteString(tk.s)
+			remain -= tk.byteLen
+			continue
+		}
+		// Need to cut inside this token
+		if tk.isANSI {
+			// Never include partial ANSI; skip it (acts like still buffering).
+			break
+		}
+		// Cut plain text at rune boundaries within byte budget
+		wrote := writeRunesWithinBytes(&b, tk.s, remain)
+		remain -= wrote
+		break
+	}
+	return b.String()
+}
+
+func writeRunesWithinBytes(b *strings.Builder, s string, budget int) int {
+	// Append as many runes as fit within 'budget' bytes (UTF-8)
+	written := 0
+	for _, r := range s {
+		n := utf8.RuneLen(r)
+		if n < 0 {
+			n = 1
+		}
+		if written+n > budget {
+			break
+		}
+		b.WriteRune(r)
+		written += n
+	}
+	return written
+}
+
+// loopPause is how long --loop leaves the finished document up.
+const loopPause = 3 * time.Second
+
+// restartStream plays the document again from the top, for --loop.
+func (m *model) restartStream() {
+	m.loopAt = time.Time{}
+	m.txStart = time.Now()
+	m.txLastAvail = 0
+	m.txBytesAvailable = 0
+	m.streamDone = false
+	m.paused = false
+	m.animating = false
+	m.autoscroll = false
+	m.view.GotoTop()
+	m.refreshContent()
+	m.rxBlink = 6
+}
+
+// togglePause freezes or resumes the stream. On resume txStart is shifted
+// forward by the paused duration so no bytes are skipped.
+func (m *model) togglePause() {
+	if m.paused {
+		m.txStart = m.txStart.Add(time.Since(m.pausedAt))
+		m.paused = false
+		return
+	}
+	m.paused = true
+	m.pausedAt = time.Now()
+}
+
+// setBaud changes the line speed mid-stream. txStart is moved so the bytes
+// already shown stay shown and only the rest arrive at the new rate.
+func (m *model) setBaud(rate int) {
+	if m.streamDone || m.bytesPerSecond <= 0 || rate <= 0 {
+		return
+	}
+	now := time.Now()
+	if m.paused {
+		now = m.pausedAt
+	}
+	shown := now.Sub(m.txStart).Seconds() * m.bytesPerSecond
+	m.baudrate = rate
+	m.bytesPerSecond = float64(rate) / 10.0
+	m.txStart = now.Add(-time.Duration(shown / m.bytesPerSecond * float64(time.Second)))
+	m.rxBlink = 6
+}
+
+// skipStream shows the whole document at once, ending the stream early.
+func (m *model) skipStream() {
+	if m.streamDone || m.bytesPerSecond <= 0 {
+		return
+	}
+	m.paused = false
+	// backdate the start so every byte is due by now
+	need := time.Duration(float64(m.streamTotalBytes) / m.bytesPerSecond * float64(time.Second))
+	m.txStart = time.Now().Add(-need - time.Second)
+	m.refreshContent()
+	m.buildIndexes()
+}
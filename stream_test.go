@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrepareStreamTokensOSC8(t *testing.T) {
+	open := "\x1b]8;id=1;https://example.com/a?b=c\x1b\\"
+	closeST := "\x1b]8;;\x1b\\"
+	full := "see \x1b[1m" + open + "the link" + closeST + "\x1b[0m and \x1b]8;;https://go.dev\x07go\x1b]8;;\x07.\n"
+
+	m := testModel(1, 5)
+	m.renderedFull = full
+	m.prepareStreamTokens()
+
+	var escapes []string
+	plainBytes := 0
+	for _, tk := range m.streamTokens {
+		if tk.isANSI {
+			escapes = append(escapes, tk.s)
+			continue
+		}
+		if strings.ContainsRune(tk.s, '\x1b') {
+			t.Errorf("plain token holds an escape: %q", tk.s)
+		}
+		plainBytes += tk.byteLen
+	}
+	want := []string{"\x1b[1m", open, closeST, "\x1b[0m", "\x1b]8;;https://go.dev\x07", "\x1b]8;;\x07"}
+	if strings.Join(escapes, "|") != strings.Join(want, "|") {
+		t.Errorf("escape tokens %q, want %q", escapes, want)
+	}
+	if text := "see the link and go.\n"; plainBytes != len(text) || stripANSI(full) != text {
+		t.Errorf("plain bytes %d, stripped %q; want %d, %q", plainBytes, stripANSI(full), len(text), text)
+	}
+	if m.streamTotalBytes != len(full) {
+		t.Errorf("streamTotalBytes = %d, want %d", m.streamTotalBytes, len(full))
+	}
+}
+
+// TestPartialStreamOSC8 streams the link a byte at a time and checks no
+// cut ever leaves part of an escape on screen.
+func TestPartialStreamOSC8(t *testing.T) {
+	full := "a \x1b]8;;https://example.com\x1b\\link\x1b]8;;\x1b\\ b"
+	m := testModel(1, 5)
+	m.renderedFull = full
+	m.baudrate = 10 // one byte a second
+	m.prepareStreamTokens()
+	m.paused = true
+	for n := 0; n <= len(full); n++ {
+		m.pausedAt = m.txStart.Add(time.Duration(n) * time.Second)
+		got := m.partialStreamString()
+		if !strings.HasPrefix(full, got) {
+			t.Fatalf("%d bytes: %q is not a prefix", n, got)
+		}
+		if rest := ansiRE.ReplaceAllString(got, ""); strings.ContainsRune(rest, '\x1b') {
+			t.Errorf("%d bytes: partial escape in %q", n, got)
+		}
+	}
+	if !m.streamDone {
+		t.Error("stream not done after every byte")
+	}
+}
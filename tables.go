@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ---------- wide tables ----------
+
+// In --80x25 mode glamour would squeeze a table that doesn't fit the canvas
+// until its cells wrap a few letters per line. Instead, such tables are
+// rendered on their own at their natural width and spliced back in; the
+// overflow is reached by scrolling the view left and right.
+
+var reTableDelim = regexp.MustCompile(`^\s{0,3}\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+
+// wideTable is a table swapped out of the Markdown before rendering.
+type wideTable struct {
+	placeholder string
+	src         string
+	width       int    // natural rendered width
+	rendered    string // filled in by the caller
+}
+
+func tablePlaceholder(i int) string { return fmt.Sprintf("MDNFOTABLE%04dX", i) }
+
+// extractTables replaces every table wider than wrap columns with a
+// placeholder paragraph. Tables inside fenced code are left alone.
+func extractTables(raw string, wrap int) (string, []wideTable) {
+	lines := strings.Split(raw, "\n")
+	var out []string
+	var tables []wideTable
+	fenced := false
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if t := strings.TrimSpace(line); strings.HasPrefix(t, "```") || strings.HasPrefix(t, "~~~") {
+			fenced = !fenced
+		}
+		if fenced || !strings.Contains(line, "|") || i+1 >= len(lines) || !reTableDelim.MatchString(lines[i+1]) {
+			out = append(out, line)
+			continue
+		}
+		end := i + 2
+		for end < len(lines) && strings.TrimSpace(lines[end]) != "" && strings.Contains(lines[end], "|") {
+			end++
+		}
+		block := lines[i:end]
+		if w := tableWidth(block); w > wrap {
+			t := wideTable{placeholder: tablePlaceholder(len(tables)), src: strings.Join(block, "\n"), width: w}
+			tables = append(tables, t)
+			out = append(out, "", t.placeholder, "")
+		} else {
+			out = append(out, block...)
+		}
+		i = end - 1
+	}
+	return strings.Join(out, "\n"), tables
+}
+
+// tableWidth estimates the rendered width of a table from its widest cell
+// in each column: one space either side of every cell, a separator between
+// columns, plus the document margin.
+func tableWidth(rows []string) int {
+	var cols []int
+	for i, row := range rows {
+		if i == 1 {
+			continue // delimiter row
+		}
+		for c, cell := range splitTableRow(row) {
+			if c >= len(cols) {
+				cols = append(cols, 0)
+			}
+			cols[c] = max(cols[c], displayWidth(strings.TrimSpace(cell)))
+		}
+	}
+	w := 4
+	for _, c := range cols {
+		w += c + 3
+	}
+	return w
+}
+
+func splitTableRow(row string) []string {
+	row = strings.TrimSpace(row)
+	row = strings.TrimPrefix(row, "|")
+	row = strings.TrimSuffix(row, "|")
+	return strings.Split(row, "|")
+}
+
+// injectTables swaps each placeholder line for its table's rendered lines,
+// minus the blank lines glamour puts around a lone block.
+func injectTables(rendered string, tables []wideTable) string {
+	if len(tables) == 0 {
+		return rendered
+	}
+	var out []string
+	for _, line := range strings.Split(rendered, "\n") {
+		plain := stripANSI(line)
+		var hit *wideTable
+		for i := range tables {
+			if strings.Contains(plain, tables[i].placeholder) {
+				hit = &tables[i]
+				break
+			}
+		}
+		if hit == nil {
+			out = append(out, line)
+			continue
+		}
+		tl := strings.Split(hit.rendered, "\n")
+		for len(tl) > 0 && strings.TrimSpace(stripANSI(tl[0])) == "" {
+			tl = tl[1:]
+		}
+		for len(tl) > 0 && strings.TrimSpace(stripANSI(tl[len(tl)-1])) == "" {
+			tl = tl[:len(tl)-1]
+		}
+		out = append(out, tl...)
+	}
+	return strings.Join(out, "\n")
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ---------- task lists (--edit-tasks) ----------
+
+// task is a GitHub task list item, "- [ ] text" or "- [x] text".
+type task struct {
+	mark         int // byte offset in rawMarkdown of the character between the brackets
+	done         bool
+	text         string
+	renderedLine int
+}
+
+var reTask = regexp.MustCompile(`^[ \t]*(?:[-*+]|\d+[.)])[ \t]+\[([ xX])\][ \t]+(.*)$`)
+
+// parseTasks finds the task items of raw outside fenced code, in source
+// order, and locates each in the rendered plain text.
+func parseTasks(raw, plain string) []task {
+	var out []task
+	fenced := false
+	off := 0
+	loc := newTextLocator(plain)
+	for _, line := range strings.SplitAfter(raw, "\n") {
+		start := off
+		off += len(line)
+		line = strings.TrimRight(line, "\r\n")
+		if t := strings.TrimSpace(line); strings.HasPrefix(t, "```") || strings.HasPrefix(t, "~~~") {
+			fenced = !fenced
+			continue
+		}
+		mm := reTask.FindStringSubmatchIndex(line)
+		if fenced || mm == nil {
+			continue
+		}
+		t := task{
+			mark:         start + mm[2],
+			done:         line[mm[2]] != ' ',
+			text:         strings.TrimSpace(line[mm[4]:mm[5]]),
+			renderedLine: -1,
+		}
+		// like headings, each is looked for after the previous one
+		t.renderedLine = loc.next(t.text)
+		out = append(out, t)
+	}
+	return out
+}
+
+// selectTask moves the task selection by dir and scrolls to it.
+func (m *model) selectTask(dir int) {
+	if !m.editTasks {
+		m.flash("task editing is off (start with --edit-tasks)")
+		return
+	}
+	if len(m.tasks) == 0 {
+		m.flash("no tasks")
+		return
+	}
+	switch {
+	case m.taskIndex == -1 && dir < 0:
+		m.taskIndex = len(m.tasks) - 1
+	case m.taskIndex == -1:
+		m.taskIndex = 0
+	default:
+		m.taskIndex = (m.taskIndex + dir + len(m.tasks)) % len(m.tasks)
+	}
+	t := m.tasks[m.taskIndex]
+	if t.renderedLine >= 0 {
+		m.view.SetYOffset(clamp(t.renderedLine-m.view.Height/2, 0, max(0, m.totalLines-m.view.Height)))
+	}
+	m.flash(taskLabel(t.done) + " " + t.text)
+}
+
+// taskOnScreen reports whether the selected task is visible, so Space
+// toggles it rather than pausing the stream.
+func (m *model) taskOnScreen() bool {
+	if !m.editTasks || m.taskIndex < 0 || m.taskIndex >= len(m.tasks) {
+		return false
+	}
+	l := m.tasks[m.taskIndex].renderedLine
+	return l >= m.view.YOffset && l < m.view.YOffset+m.view.Height
+}
+
+// toggleTask checks or unchecks the selected task and saves the file. The
+// file must still hold exactly what was last loaded or saved, so an edit
+// made elsewhere is never overwritten. A toggle that can't be saved is
+// still shown, and kept until the next save; quitting, reloading or leaving
+// for another document then asks before throwing it away.
+func (m *model) toggleTask() {
+	t := m.tasks[m.taskIndex]
+	b := []byte(m.rawMarkdown)
+	b[t.mark] = 'x'
+	if t.done {
+		b[t.mark] = ' '
+	}
+	err := m.saveTasks(b)
+	if err != nil && !m.tasksDirty {
+		m.tasksDirty, m.savedMarkdown = true, m.rawMarkdown
+	}
+	off := m.view.YOffset
+	m.rawMarkdown = string(b)
+	m.cache.valid = false
+	m.recalcRendered(m.view.Width, m.screenHeight())
+	m.view.SetYOffset(clamp(off, 0, max(0, m.totalLines-m.view.Height)))
+	if err != nil {
+		m.flash("not saved: " + err.Error())
+		return
+	}
+	m.flash(taskLabel(!t.done) + " " + t.text)
+}
+
+// saveTasks writes b, the document with its tasks toggled, over the file.
+// Earlier toggles that failed to save go with it.
+func (m *model) saveTasks(b []byte) error {
+	if m.filename == stdinName {
+		return errors.New("stdin has no file")
+	}
+	disk, err := os.ReadFile(m.filename)
+	if err != nil {
+		return err
+	}
+	want := m.rawMarkdown
+	if m.tasksDirty {
+		want = m.savedMarkdown
+	}
+	if string(disk) != want {
+		return errors.New("file changed on disk or is not UTF-8")
+	}
+	fi, err := os.Stat(m.filename)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(m.filename, b, fi.Mode().Perm()); err != nil {
+		return err
+	}
+	m.tasksDirty, m.savedMarkdown = false, ""
+	// take the write as seen, so --watch doesn't reload it again
+	if fi, err := os.Stat(m.filename); err == nil {
+		m.fileMod = fi.ModTime()
+	}
+	return nil
+}
+
+// confirmQuit quits, unless task toggles are unsaved: then it asks first.
+func (m *model) confirmQuit() tea.Cmd {
+	return m.confirmLeave((*model).quit)
+}
+
+// confirmLeave runs leave, which replaces the document on screen (quitting,
+// reloading, switching files, following a link or going back), unless task
+// toggles are unsaved: then it asks first.
+func (m *model) confirmLeave(leave func(*model) tea.Cmd) tea.Cmd {
+	if !m.tasksDirty {
+		return leave(m)
+	}
+	m.pendingLeave = leave
+	m.mode = modeDiscard
+	return nil
+}
+
+// updateDiscard takes the answer to "discard changes?": y goes on with
+// the leave that asked, anything else stays on the document.
+func (m *model) updateDiscard(msg tea.KeyMsg) tea.Cmd {
+	m.mode = modeNormal
+	leave := m.pendingLeave
+	m.pendingLeave = nil
+	if k := msg.String(); (k == "y" || k == "Y") && leave != nil {
+		return leave(m)
+	}
+	m.flash("changes kept; Space on a task retries the save")
+	return m.tick()
+}
+
+func taskLabel(done bool) string {
+	if done {
+		return "[x]"
+	}
+	return "[ ]"
+}
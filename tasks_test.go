@@ -0,0 +1,169 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// TestToggleTaskKeepsHeight toggles a task in each chrome layout: the view
+// must stay the height it was, --minimal (no header or footer) included.
+func TestToggleTaskKeepsHeight(t *testing.T) {
+	for _, minimal := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "tasks.md")
+		raw := "# Tasks\n\n- [ ] one\n- [ ] two\n"
+		if err := os.WriteFile(path, []byte(raw), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		m := docModel(raw, "dark")
+		m.filename, m.editTasks, m.minimal = path, true, minimal
+		m.recalcRendered(80, 24)
+		height := m.view.Height
+		for range 3 {
+			m.taskIndex = 0
+			m.toggleTask()
+			if m.view.Height != height {
+				t.Fatalf("minimal %v: view height %d after a toggle, was %d", minimal, m.view.Height, height)
+			}
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "# Tasks\n\n- [x] one\n- [ ] two\n"; string(b) != want {
+			t.Errorf("minimal %v: file is %q, want %q", minimal, b, want)
+		}
+	}
+}
+
+// dirtyTasks is a model showing a.md, next to b.md, with a toggle that
+// failed to save: the file changed while it was being made, then changed
+// back.
+func dirtyTasks(t *testing.T) (m model, a, b string) {
+	t.Helper()
+	dir := t.TempDir()
+	a, b = filepath.Join(dir, "a.md"), filepath.Join(dir, "b.md")
+	raw := "# A\n\n- [ ] one\n\nSee [b](b.md).\n"
+	for name, text := range map[string]string{a: "edited elsewhere", b: "# B\n"} {
+		if err := os.WriteFile(name, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m = docModel(raw, "dark")
+	m.filename, m.files, m.editTasks = a, []string{a, b}, true
+	m.recalcRendered(80, 24)
+	m.taskIndex = 0
+	m.toggleTask()
+	if !m.tasksDirty {
+		t.Fatal("the toggle saved over a changed file")
+	}
+	if err := os.WriteFile(a, []byte(raw), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return m, a, b
+}
+
+func key(k string) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)} }
+
+func TestLeaveAsksWithUnsavedToggles(t *testing.T) {
+	leaves := []struct {
+		name  string
+		leave func(m *model) tea.Cmd
+	}{
+		{"quit", func(m *model) tea.Cmd { cmd, _ := m.handleAction(actQuit); return cmd }},
+		{"next file", func(m *model) tea.Cmd { cmd, _ := m.handleAction(actNextFile); return cmd }},
+		{"prev file", func(m *model) tea.Cmd { cmd, _ := m.handleAction(actPrevFile); return cmd }},
+		{"link", func(m *model) tea.Cmd { return m.followLink(link{text: "b", target: "b.md", renderedLine: -1}) }},
+		{"reload", func(m *model) tea.Cmd { cmd, _ := m.handleAction(actReload); return cmd }},
+		{"editor", func(m *model) tea.Cmd { next, cmd := m.update(editorDoneMsg{}); *m = next.(model); return cmd }},
+	}
+	for _, tt := range leaves {
+		m, a, b := dirtyTasks(t)
+		if cmd := tt.leave(&m); cmd != nil || m.mode != modeDiscard || m.filename != a {
+			t.Fatalf("%s: left without asking (mode %d, showing %s)", tt.name, m.mode, m.filename)
+		}
+		m.updateDiscard(key("n"))
+		if m.mode != modeNormal || m.filename != a || !m.tasksDirty {
+			t.Fatalf("%s: n didn't stay (mode %d, showing %s, dirty %v)", tt.name, m.mode, m.filename, m.tasksDirty)
+		}
+		tt.leave(&m)
+		m.updateDiscard(key("y"))
+		switch tt.name {
+		case "quit":
+			if !m.quitting {
+				t.Errorf("quit: y didn't quit")
+			}
+			continue
+		case "reload", "editor":
+			if disk, _ := os.ReadFile(a); m.filename != a || m.tasksDirty || m.rawMarkdown != string(disk) {
+				t.Errorf("%s: after y dirty %v, showing %q; want the file %q", tt.name, m.tasksDirty, m.rawMarkdown, disk)
+			}
+			continue
+		}
+		if m.filename != b || m.tasksDirty {
+			t.Errorf("%s: after y showing %s, dirty %v; want %s", tt.name, m.filename, m.tasksDirty, b)
+		}
+	}
+}
+
+// TestBackRestoresFileOnDisk leaves unsaved toggles by a link and comes
+// back: the text shown is the file's, so the next toggle saves.
+func TestBackRestoresFileOnDisk(t *testing.T) {
+	m, a, _ := dirtyTasks(t)
+	disk, err := os.ReadFile(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.followLink(link{text: "b", target: "b.md", renderedLine: -1})
+	m.updateDiscard(key("y"))
+
+	if m.handleAction(actBack); m.mode != modeNormal {
+		t.Fatalf("back asked again (mode %d)", m.mode)
+	}
+	if m.filename != a || m.rawMarkdown != string(disk) {
+		t.Fatalf("back showed %s: %q, want the file on disk %q", m.filename, m.rawMarkdown, disk)
+	}
+	m.taskIndex = 0
+	m.toggleTask()
+	if m.tasksDirty {
+		t.Fatal("the toggle after going back didn't save")
+	}
+	if got, _ := os.ReadFile(a); string(got) != "# A\n\n- [x] one\n\nSee [b](b.md).\n" {
+		t.Errorf("file is %q", got)
+	}
+}
+
+// TestBackWithinDocumentKeepsToggles jumps to an anchor and back in the
+// same document: nothing is left, so nothing is asked or lost.
+func TestBackWithinDocumentKeepsToggles(t *testing.T) {
+	m, a, _ := dirtyTasks(t)
+	toggled := m.rawMarkdown
+	m.followLink(link{text: "A", target: "#a", renderedLine: -1})
+	m.handleAction(actBack)
+	if m.mode != modeNormal || m.filename != a || m.rawMarkdown != toggled || !m.tasksDirty {
+		t.Errorf("back in the document: mode %d, dirty %v, text %q", m.mode, m.tasksDirty, m.rawMarkdown)
+	}
+}
+
+// TestWatchKeepsUnsavedToggles changes the file under unsaved toggles with
+// --watch on: they stay, with a warning, rather than being reloaded away.
+func TestWatchKeepsUnsavedToggles(t *testing.T) {
+	m, a, _ := dirtyTasks(t)
+	m.watch = true
+	toggled := m.rawMarkdown
+	if err := os.WriteFile(a, []byte("# A\n\nrewritten\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	next, _ := m.update(fileChangedMsg{path: a, mod: m.fileMod.Add(time.Second)})
+	m = next.(model)
+	if m.rawMarkdown != toggled || !m.tasksDirty || m.mode != modeNormal {
+		t.Errorf("watch reload: dirty %v, mode %d, text %q; want the toggles kept", m.tasksDirty, m.mode, m.rawMarkdown)
+	}
+	if !strings.Contains(m.statusMsg, "unsaved") {
+		t.Errorf("no warning, status %q", m.statusMsg)
+	}
+}
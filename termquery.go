@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// ---------- terminal queries ----------
+
+// reDA1 is the primary device attributes reply, the end of the answer.
+var reDA1 = regexp.MustCompile(`\x1b\[\?[0-9;]*c`)
+
+// queryTerminal writes query to /dev/tty in raw mode and returns what the
+// terminal answers within timeout. A DA1 request follows the query, so
+// terminals that ignore it don't make us wait out the timeout.
+func queryTerminal(query string, timeout time.Duration) []byte {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil
+	}
+	defer tty.Close()
+	// without a deadline a silent terminal would block startup
+	if err := tty.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return nil
+	}
+	// Fd() would switch the file to blocking mode and void the deadline
+	rc, err := tty.SyscallConn()
+	if err != nil {
+		return nil
+	}
+	var state *term.State
+	if err := rc.Control(func(fd uintptr) { state, err = term.MakeRaw(int(fd)) }); err != nil || state == nil {
+		return nil
+	}
+	defer rc.Control(func(fd uintptr) { _ = term.Restore(int(fd), state) })
+
+	if _, err := tty.WriteString(query + "\x1b[c"); err != nil {
+		return nil
+	}
+	var got []byte
+	buf := make([]byte, 256)
+	for {
+		n, err := tty.Read(buf)
+		got = append(got, buf[:n]...)
+		if err != nil || reDA1.Match(got) {
+			return got
+		}
+	}
+}
+
+// reOSC11 is the reply to an OSC 11 background color query.
+var reOSC11 = regexp.MustCompile(`\x1b\]11;rgb:([0-9a-fA-F]{1,4})/([0-9a-fA-F]{1,4})/([0-9a-fA-F]{1,4})`)
+
+// queryBackground asks the terminal for its background color (OSC 11);
+// ok is false when it doesn't say within timeout.
+func queryBackground(timeout time.Duration) (dark, ok bool) {
+	mm := reOSC11.FindSubmatch(queryTerminal("\x1b]11;?\x1b\\", timeout))
+	if mm == nil {
+		return false, false
+	}
+	var c [3]float64
+	for i, h := range mm[1:] {
+		n, _ := strconv.ParseUint(string(h), 16, 16)
+		c[i] = float64(n) / float64(uint64(1)<<(4*len(h))-1)
+	}
+	// relative luminance; below the middle grey is a dark background
+	return 0.2126*c[0]+0.7152*c[1]+0.0722*c[2] < 0.5, true
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ---------- table of contents ----------
+
+func (m *model) updateToc(msg tea.KeyMsg) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.mode = modeNormal
+	case tea.KeyUp:
+		m.tocIndex = max(0, m.tocIndex-1)
+	case tea.KeyDown:
+		m.tocIndex = min(len(m.headings)-1, m.tocIndex+1)
+	case tea.KeyPgUp:
+		m.tocIndex = max(0, m.tocIndex-m.tocRows())
+	case tea.KeyPgDown:
+		m.tocIndex = min(len(m.headings)-1, m.tocIndex+m.tocRows())
+	case tea.KeyHome:
+		m.tocIndex = 0
+	case tea.KeyEnd:
+		m.tocIndex = len(m.headings) - 1
+	case tea.KeyEnter:
+		m.mode = modeNormal
+		if h := m.headings[m.tocIndex]; h.renderedLine >= 0 {
+			m.record()
+			m.view.SetYOffset(clamp(h.renderedLine, 0, max(0, m.totalLines-m.view.Height)))
+		}
+	default:
+		if strings.ToLower(msg.String()) == "t" {
+			m.mode = modeNormal
+		}
+	}
+}
+
+func (m *model) updateHelp(msg tea.KeyMsg) {
+	last := max(0, len(m.keys.helpLines())-m.helpRows())
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.mode = modeNormal
+	case tea.KeyUp:
+		m.helpOffset = max(0, m.helpOffset-1)
+	case tea.KeyDown:
+		m.helpOffset = min(last, m.helpOffset+1)
+	case tea.KeyPgUp:
+		m.helpOffset = max(0, m.helpOffset-m.helpRows())
+	case tea.KeyPgDown:
+		m.helpOffset = min(last, m.helpOffset+m.helpRows())
+	case tea.KeyHome:
+		m.helpOffset = 0
+	case tea.KeyEnd:
+		m.helpOffset = last
+	default:
+		switch msg.String() {
+		case "?":
+			m.mode = modeNormal
+		case "k":
+			m.helpOffset = max(0, m.helpOffset-1)
+		case "j":
+			m.helpOffset = min(last, m.helpOffset+1)
+		}
+	}
+}
+
+// helpRows is how many help lines fit inside the overlay box.
+func (m *model) helpRows() int {
+	return max(1, m.view.Height-2)
+}
+
+func (m *model) helpOverlay(body string, width int) string {
+	lines := m.keys.helpLines()
+	rows := min(len(lines), m.helpRows())
+	m.helpOffset = clamp(m.helpOffset, 0, len(lines)-rows)
+	visible := lines[m.helpOffset : m.helpOffset+rows]
+
+	title := " Keys "
+	if rows < len(lines) {
+		title = fmt.Sprintf(" Keys %d-%d/%d ", m.helpOffset+1, m.helpOffset+rows, len(lines))
+	}
+	inner := displayWidth(title)
+	for _, l := range visible {
+		inner = max(inner, displayWidth(l))
+	}
+	inner = min(inner, max(1, width-6))
+	return placeOverlay(body, drawBox(title, visible, inner, -1), width)
+}
+
+// currentHeading is the index of the last heading at or above the top line.
+func (m *model) currentHeading() int {
+	cur := 0
+	for i, h := range m.headings {
+		if h.renderedLine >= 0 && h.renderedLine <= m.view.YOffset {
+			cur = i
+		}
+	}
+	return cur
+}
+
+// breadcrumb is the heading path down to the section the top line is
+// in, e.g. "Installation > Linux".
+func (m *model) breadcrumb() string {
+	var path []heading
+	for _, h := range m.headings {
+		if h.renderedLine < 0 || h.renderedLine > m.view.YOffset {
+			continue
+		}
+		// a heading closes the sections at its level and deeper
+		for len(path) > 0 && path[len(path)-1].level >= h.level {
+			path = path[:len(path)-1]
+		}
+		path = append(path, h)
+	}
+	names := make([]string, len(path))
+	for i, h := range path {
+		names[i] = h.text
+	}
+	return strings.Join(names, " > ")
+}
+
+// tocRows is how many entries fit inside the overlay box.
+func (m *model) tocRows() int {
+	return max(1, min(len(m.headings), m.view.Height-4))
+}
+
+func (m *model) tocOverlay(body string, width int) string {
+	rows := m.tocRows()
+	if m.tocIndex < m.tocOffset {
+		m.tocOffset = m.tocIndex
+	}
+	if m.tocIndex >= m.tocOffset+rows {
+		m.tocOffset = m.tocIndex - rows + 1
+	}
+
+	selected := m.tocIndex - m.tocOffset
+	entries := make([]string, 0, rows)
+	for i := m.tocOffset; i < m.tocOffset+rows && i < len(m.headings); i++ {
+		h := m.headings[i]
+		e := strings.Repeat("  ", h.level-1) + h.text
+		if m.noColor {
+			// no inverse video: mark the selection with a pointer
+			if i == m.tocIndex {
+				e = "> " + e
+			} else {
+				e = "  " + e
+			}
+		}
+		entries = append(entries, e)
+	}
+	if m.noColor {
+		selected = -1
+	}
+	inner := 0
+	for _, e := range entries {
+		inner = max(inner, displayWidth(e))
+	}
+	inner = clamp(inner, displayWidth(" Contents "), max(1, width-6))
+
+	box := drawBox(" Contents ", entries, inner, selected)
+	return placeOverlay(body, box, width)
+}
+
+// drawBox frames lines (clipped/padded to inner columns) with a titled
+// single-line border; lines[selected] is shown in inverse video.
+func drawBox(title string, lines []string, inner, selected int) []string {
+	top := "┌─" + title + strings.Repeat("─", max(0, inner+1-displayWidth(title))) + "┐"
+	out := []string{top}
+	for i, l := range lines {
+		l = padToWidth(truncateToWidth(l, inner), inner)
+		if i == selected {
+			l = "\x1b[7m" + l + "\x1b[27m"
+		}
+		out = append(out, "│ "+l+" │")
+	}
+	return append(out, "└"+strings.Repeat("─", inner+2)+"┘")
+}
+
+// errorView stands in for the document while it can't be rendered: the
+// error, centered, and how to recover.
+func (m model) errorView() string {
+	w, h := m.view.Width, m.screenHeight()
+	if w <= 0 {
+		w, h = terminalSize()
+	}
+	lines := []string{"can't render " + m.filename, ""}
+	for _, l := range strings.Split(m.err.Error(), "\n") {
+		lines = append(lines, "  "+l)
+	}
+	lines = append(lines, "")
+	if m.filename == stdinName {
+		lines = append(lines, "press q to quit")
+	} else {
+		lines = append(lines, "fix the file and press r to reload (automatic with --watch), or q to quit")
+	}
+	if m.statusFrames > 0 {
+		lines = append(lines, "", m.statusMsg)
+	}
+	widest := 0
+	for _, l := range lines {
+		widest = max(widest, displayWidth(l))
+	}
+	pad := max(0, (w-widest)/2)
+	for i, l := range lines {
+		lines[i] = strings.Repeat(" ", pad) + truncateToWidth(l, w-pad)
+	}
+	return strings.Repeat("\n", max(0, (h-len(lines))/2)) + strings.Join(lines, "\n")
+}
+
+// placeOverlay centers box (whose first row must be plain text) over body;
+// covered rows are replaced whole.
+func placeOverlay(body string, box []string, width int) string {
+	lines := strings.Split(body, "\n")
+	if len(box) == 0 {
+		return body
+	}
+	top := max(0, (len(lines)-len(box))/2)
+	pad := strings.Repeat(" ", max(0, (width-displayWidth(box[0]))/2))
+	for i, b := range box {
+		if top+i >= len(lines) {
+			break
+		}
+		lines[top+i] = pad + b
+	}
+	return strings.Join(lines, "\n")
+}
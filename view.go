@@ -0,0 +1,340 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// ---------- view ----------
+
+func (m model) View() string {
+	if m.err != nil {
+		return m.errorView()
+	}
+	if m.quitting && m.inline {
+		return m.finalView()
+	}
+	w := m.view.Width
+	if w <= 0 {
+		w, _ = terminalSize()
+	}
+
+	// Right side: file mod time (per --time) + human size + caps
+	caps := "TC"
+	if !m.truecolor && m.palette256 {
+		caps = "256"
+	}
+	if !m.truecolor && !m.palette256 {
+		caps = "16"
+	}
+
+	right := fmt.Sprintf("%s [%s]", humanSize(m.fileSize), caps)
+	if stamp := m.timeFormat.format(m.fileMod, time.Now()); stamp != "" {
+		right = stamp + " " + right
+	}
+
+	left := m.filename
+	if len(m.files) > 1 {
+		left = fmt.Sprintf("%d/%d %s", m.fileIndex+1, len(m.files), left)
+	}
+	available := w - displayWidth(right) - 1
+	if available < 1 {
+		available = 1
+	}
+	if crumb := m.breadcrumb(); crumb != "" {
+		left += "  § " + crumb
+	}
+
+	// Mode indicators
+	badges := []string{}
+	if m.fixed8025 {
+		badges = append(badges, "80x25")
+	}
+	if m.scanlines {
+		badges = append(badges, "Scanlines")
+	}
+	if m.mono != monoOff {
+		badges = append(badges, "Mono:"+m.mono.String())
+	}
+	if m.inverse {
+		badges = append(badges, "Inverse")
+	}
+	if m.bbsChrome {
+		badges = append(badges, "BBS")
+	}
+	if m.xOffset > 0 {
+		badges = append(badges, fmt.Sprintf("Col:+%d", m.xOffset))
+	}
+	if b := m.bookmarkBadge(); b != "" {
+		badges = append(badges, b)
+	}
+	if m.wrapBadgeFrames > 0 {
+		if m.wrapWidth > 0 {
+			badges = append(badges, fmt.Sprintf("Wrap:%d", m.wrapWidth))
+		} else {
+			badges = append(badges, "Wrap:auto")
+		}
+	}
+	if m.baudrate > 0 && !m.streamDone {
+		if m.paused {
+			badges = append(badges, "RX paused")
+		} else {
+			badges = append(badges, fmt.Sprintf("RX %.0fB/s", m.bytesPerSecond))
+		}
+	}
+	if len(badges) > 0 {
+		left = left + "  [" + strings.Join(badges, " | ") + "]"
+	}
+	left = truncateToWidth(left, available)
+
+	header := padToWidth(left, available) + " " + right
+
+	// current line = last visible line, capped at total
+	current := m.view.YOffset + m.view.Height
+	if current > m.totalLines {
+		current = m.totalLines
+	}
+	if current < 1 && m.totalLines > 0 {
+		current = 1
+	}
+	total := max(1, m.totalLines)
+
+	// progress ratio based on scroll offset (start 0, end 1 at bottom)
+	ratio := 0.0
+	den := float64(max(1, m.totalLines-m.view.Height))
+	if den > 0 {
+		ratio = float64(m.view.YOffset) / den
+		if ratio < 0 {
+			ratio = 0
+		}
+		if ratio > 1 {
+			ratio = 1
+		}
+	}
+	label := m.progress.label(ratio, current, total)
+	if b := m.searchBadge(); b != "" {
+		label += "  " + b
+	}
+	progress := drawProgressBar(w, ratio, label, m.barChars)
+
+	footer := progress
+	if m.bbsChrome {
+		footer = m.bbsStatusLine(w)
+	}
+	if m.statusFrames > 0 {
+		footer = padToWidth(truncateToWidth(" "+m.statusMsg, w), w)
+	}
+	if p := m.mode.prompt(); p != "" {
+		footer = padToWidth(truncateToWidth(p+m.input, w), w)
+	}
+
+	body := m.view.View()
+	if m.ghostFrames > 0 {
+		body = blendGhost(body, m.ghost)
+	}
+	if m.cursor && cursorVisible(time.Now()) {
+		body = m.drawCursor(body)
+	}
+	switch m.mode {
+	case modeToc:
+		body = m.tocOverlay(body, w)
+	case modeHelp:
+		body = m.helpOverlay(body, w)
+	case modeInfo:
+		body = m.infoOverlay(body, w)
+	}
+	if m.minimal {
+		// prompts and messages borrow the last line instead
+		if m.mode.prompt() != "" || m.statusFrames > 0 {
+			lines := strings.Split(body, "\n")
+			lines[len(lines)-1] = footer
+			body = strings.Join(lines, "\n")
+		}
+		return body
+	}
+
+	return header + "\n" + body + "\n" + footer
+}
+
+func (m model) bbsStatusLine(w int) string {
+	// e.g., " CONNECT 115200  RX:· TX:·  [s]canlines [m]ono [b]bs [d]egauss  [q]uit "
+	rx := "·"
+	tx := "·"
+	if m.rxBlink > 0 {
+		rx = "●"
+	}
+	if m.txBlink > 0 {
+		tx = "●"
+	}
+	conn := fmt.Sprintf("CONNECT %d", m.baudrate)
+	if m.paused && !m.streamDone {
+		conn += " (PAUSED)"
+	}
+	if b := m.searchBadge(); b != "" {
+		conn += "  " + b
+	}
+	label := fmt.Sprintf(" %s  RX:%s TX:%s  [s]canlines [m]ono [b]bs [d]egauss  [q]uit ", conn, rx, tx)
+	return padToWidth(truncateToWidth(label, w), w)
+}
+
+// cursorVisible blinks the cursor twice a second.
+func cursorVisible(now time.Time) bool {
+	return now.UnixMilli()/250%2 == 0
+}
+
+// drawCursor puts a block cursor on the viewport body: after the last
+// streamed character while streaming, else at the bottom-right corner.
+// It is drawn at View time so it never reaches renderedLines or the indexes.
+func (m model) drawCursor(body string) string {
+	lines := strings.Split(body, "\n")
+	if len(lines) == 0 {
+		return body
+	}
+	row, col := len(lines)-1, max(0, m.view.Width-1)
+	if !m.streamDone {
+		row = m.totalLines - 1 - m.view.YOffset
+		if row < 0 || row >= len(lines) {
+			return body
+		}
+		col = displayWidth(strings.TrimRight(stripANSI(lines[row]), " "))
+	}
+	lines[row] = replaceColumn(lines[row], col, "█")
+	return strings.Join(lines, "\n")
+}
+
+// replaceColumn overwrites the visible rune at column col of line with s
+// (appending if the line is shorter), stepping over ANSI sequences.
+func replaceColumn(line string, col int, s string) string {
+	seqs := ansiRE.FindAllStringIndex(line, -1)
+	var b strings.Builder
+	c := 0
+	for i := 0; i < len(line); {
+		if len(seqs) > 0 && i == seqs[0][0] {
+			b.WriteString(line[seqs[0][0]:seqs[0][1]])
+			i = seqs[0][1]
+			seqs = seqs[1:]
+			continue
+		}
+		r, n := utf8.DecodeRuneInString(line[i:])
+		i += n
+		if c == col {
+			b.WriteString(s)
+			b.WriteString(line[i:])
+			return b.String()
+		}
+		b.WriteRune(r)
+		c += runewidth.RuneWidth(r)
+	}
+	return b.String() + strings.Repeat(" ", max(0, col-c)) + s
+}
+
+// progressLabel is what the progress bar's label shows, --progress.
+type progressLabel int
+
+const (
+	progressLines   progressLabel = iota // 120 / 285
+	progressPercent                      // 42%
+	progressBoth                         // 42%  120/285
+)
+
+func (p progressLabel) label(ratio float64, current, total int) string {
+	pct := int(ratio*100 + 0.5)
+	switch p {
+	case progressPercent:
+		return fmt.Sprintf(" %d%% ", pct)
+	case progressBoth:
+		return fmt.Sprintf(" %d%%  %d/%d ", pct, current, total)
+	default:
+		return fmt.Sprintf(" %d / %d ", current, total)
+	}
+}
+
+// barChars are the progress bar's fill and empty glyphs, one column each.
+type barChars [2]rune
+
+// barStyles are the --bar-style presets.
+var barStyles = map[string]barChars{
+	"blocks": {'█', '░'},
+	"shades": {'▓', '░'},
+	"ascii":  {'#', '-'},
+}
+
+// parseBarChars reads --bar-chars: exactly two single-column runes.
+func parseBarChars(s string) (barChars, error) {
+	r := []rune(s)
+	if len(r) != 2 || runewidth.RuneWidth(r[0]) != 1 || runewidth.RuneWidth(r[1]) != 1 {
+		return barChars{}, fmt.Errorf("invalid --bar-chars %q: want two single-width characters, fill then empty (e.g. \"#-\")", s)
+	}
+	return barChars{r[0], r[1]}, nil
+}
+
+func drawProgressBar(width int, ratio float64, label string, chars barChars) string {
+	fillGlyph, emptyGlyph := string(chars[0]), string(chars[1])
+	if width < 3 {
+		return strings.Repeat(fillGlyph, width)
+	}
+	fill := int(float64(width) * ratio)
+	if fill < 0 {
+		fill = 0
+	}
+	if fill > width {
+		fill = width
+	}
+	var b strings.Builder
+	b.Grow(width)
+	b.WriteString(strings.Repeat(fillGlyph, fill))
+	if fill < width {
+		b.WriteString(strings.Repeat(emptyGlyph, width-fill))
+	}
+	bar := b.String()
+
+	// the bar's glyphs are one column each, so runes index columns
+	if lw := displayWidth(label); lw > 0 && lw < width {
+		start := (width - lw) / 2
+		runes := []rune(bar)
+		bar = string(runes[:start]) + label + string(runes[start+lw:])
+	}
+	return bar
+}
+
+// displayWidth is the terminal column width of s; East Asian wide runes
+// and emoji count as 2.
+func displayWidth(s string) int {
+	return runewidth.StringWidth(s)
+}
+
+// truncateVisibleToWidth truncates by visible width (ANSI-safe) for simple UI strings we control.
+func truncateVisibleToWidth(s string, w int) string {
+	plain := stripANSI(s)
+	if displayWidth(plain) <= w {
+		return s
+	}
+	return truncateToWidth(plain, w)
+}
+
+// truncateToWidth cuts s to at most w columns, never splitting a wide rune.
+func truncateToWidth(s string, w int) string {
+	if displayWidth(s) <= w {
+		return s
+	}
+	var b strings.Builder
+	cols := 0
+	for _, r := range s {
+		rw := runewidth.RuneWidth(r)
+		if cols+rw > w {
+			break
+		}
+		b.WriteRune(r)
+		cols += rw
+	}
+	return b.String()
+}
+
+// padToWidth right-pads s with spaces to w columns.
+func padToWidth(s string, w int) string {
+	return s + strings.Repeat(" ", max(0, w-displayWidth(s)))
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"", 0},
+		{"abc", 3},
+		{"日本語", 6},
+		{"a日b", 4},
+		{"🙂", 2},
+		{"x🙂y", 4},
+		{"한국어 ok", 9},
+	}
+	for _, tt := range tests {
+		if got := displayWidth(tt.in); got != tt.want {
+			t.Errorf("displayWidth(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateToWidth(t *testing.T) {
+	tests := []struct {
+		in   string
+		w    int
+		want string
+	}{
+		{"abcdef", 3, "abc"},
+		{"abc", 5, "abc"},
+		{"日本語", 6, "日本語"},
+		{"日本語", 5, "日本"},
+		{"日本語", 3, "日"},
+		{"日本語", 1, ""},
+		{"a日b", 2, "a"},
+		{"🙂🙂🙂", 5, "🙂🙂"},
+		{"x🙂y", 2, "x"},
+	}
+	for _, tt := range tests {
+		got := truncateToWidth(tt.in, tt.w)
+		if got != tt.want {
+			t.Errorf("truncateToWidth(%q, %d) = %q, want %q", tt.in, tt.w, got, tt.want)
+		}
+		if displayWidth(got) > tt.w {
+			t.Errorf("truncateToWidth(%q, %d) is %d columns wide", tt.in, tt.w, displayWidth(got))
+		}
+	}
+}
+
+func TestTruncateVisibleToWidth(t *testing.T) {
+	tests := []struct {
+		in   string
+		w    int
+		want string
+	}{
+		{"\x1b[1mabc\x1b[0m", 3, "\x1b[1mabc\x1b[0m"},
+		{"\x1b[1m日本語\x1b[0m", 5, "日本"},
+		{"\x1b[31m🙂x\x1b[0m", 2, "🙂"},
+	}
+	for _, tt := range tests {
+		if got := truncateVisibleToWidth(tt.in, tt.w); got != tt.want {
+			t.Errorf("truncateVisibleToWidth(%q, %d) = %q, want %q", tt.in, tt.w, got, tt.want)
+		}
+	}
+}
+
+// TestHeaderAlignment checks the header's left side, truncated and padded
+// the way View does it, always fills its columns exactly.
+func TestHeaderAlignment(t *testing.T) {
+	lefts := []string{
+		"README.md",
+		"日本語のドキュメント.md  § 概要",
+		"🙂 notes.md  [Scanlines | Mono:green]",
+		"mixed 中文 and ascii and 🙂🙂🙂 and more",
+	}
+	right := "2024-01-02T03:04:05Z 1.2 KiB [TC]"
+	for _, w := range []int{20, 41, 60, 80, 121} {
+		available := max(1, w-displayWidth(right)-1)
+		for _, left := range lefts {
+			header := padToWidth(truncateToWidth(left, available), available) + " " + right
+			if got := displayWidth(header); got != max(w, displayWidth(right)+2) {
+				t.Errorf("width %d, left %q: header is %d columns", w, left, got)
+			}
+		}
+	}
+}
+
+func TestProgressLabel(t *testing.T) {
+	tests := []struct {
+		p    progressLabel
+		want string
+	}{
+		{progressLines, " 120 / 285 "},
+		{progressPercent, " 42% "},
+		{progressBoth, " 42%  120/285 "},
+	}
+	for _, tt := range tests {
+		if got := tt.p.label(120.0/285, 120, 285); got != tt.want {
+			t.Errorf("label(%d) = %q, want %q", tt.p, got, tt.want)
+		}
+	}
+}
+
+// TestDrawProgressBarCentering checks the label sits in the middle by
+// columns, not bytes, and the bar keeps its width, for labels with
+// multibyte and double-width runes.
+func TestDrawProgressBarCentering(t *testing.T) {
+	tests := []struct {
+		width int
+		label string
+		start int // column the label starts at
+	}{
+		{20, " 42% ", 7},
+		{21, " 42% ", 8},
+		{20, " 42 ‰ ", 7},         // multibyte, one column
+		{20, " 四二% ", 6},          // 7 columns, 11 bytes
+		{30, " 🙂 12 / 34 ", 9},    // emoji are two columns
+		{10, " ░░ ", 3},           // the empty glyph itself
+		{8, " far too long ", -1}, // doesn't fit: no label
+	}
+	for _, tt := range tests {
+		for _, chars := range []barChars{barStyles["blocks"], barStyles["ascii"]} {
+			bar := drawProgressBar(tt.width, 0.5, tt.label, chars)
+			if got := displayWidth(bar); got != tt.width {
+				t.Errorf("%q at %d: bar is %d columns", tt.label, tt.width, got)
+			}
+			at := strings.Index(bar, tt.label)
+			if tt.start < 0 {
+				if at >= 0 {
+					t.Errorf("%q at %d: label drawn though it doesn't fit", tt.label, tt.width)
+				}
+				continue
+			}
+			if at < 0 {
+				t.Errorf("%q at %d: label missing from %q", tt.label, tt.width, bar)
+				continue
+			}
+			if col := displayWidth(bar[:at]); col != tt.start {
+				t.Errorf("%q at %d: label at column %d, want %d", tt.label, tt.width, col, tt.start)
+			}
+		}
+	}
+}